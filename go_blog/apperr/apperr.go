@@ -0,0 +1,135 @@
+// Package apperr 定义业务错误的类型，控制器据此决定状态码和返回给客户端的内容。
+// 内部错误和上游错误的原因只写入日志，客户端只能看到 Message。
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind 错误类型
+type Kind int
+
+const (
+	// Internal 内部错误，如数据库失败
+	Internal Kind = iota
+	// NotFound 请求的资源不存在
+	NotFound
+	// Invalid 请求参数或提交的内容无效
+	Invalid
+	// Upstream 上游服务失败，如 AI 服务商
+	Upstream
+	// Forbidden 没有权限，如 CSRF 校验失败
+	Forbidden
+	// RateLimited 请求过于频繁
+	RateLimited
+	// Unavailable 功能暂不可用，如 AI 预算已用完
+	Unavailable
+	// Conflict 与当前状态冲突，如提问次数已达上限
+	Conflict
+	// Unauthorized 未登录或凭证无效
+	Unauthorized
+)
+
+// 内部错误默认展示的提示
+const internalMessage = "服务器内部错误，请稍后重试"
+
+// Error 业务错误
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Err     error
+}
+
+// Error 返回展示给用户的提示，不包含原因
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap 返回原因，便于 errors.Is 判断
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status 错误类型对应的 HTTP 状态码
+func (e *Error) Status() int {
+	switch e.Kind {
+	case NotFound:
+		return http.StatusNotFound
+	case Invalid:
+		return http.StatusBadRequest
+	case Upstream:
+		return http.StatusBadGateway
+	case Forbidden:
+		return http.StatusForbidden
+	case RateLimited:
+		return http.StatusTooManyRequests
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case Conflict:
+		return http.StatusConflict
+	case Unauthorized:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Logged 是否需要写入日志，内部错误和上游错误需要
+func (e *Error) Logged() bool {
+	return e.Kind == Internal || e.Kind == Upstream
+}
+
+// New 创建指定类型的业务错误
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// NewNotFound 资源不存在
+func NewNotFound(code, message string) *Error {
+	return &Error{Kind: NotFound, Code: code, Message: message}
+}
+
+// NewInvalid 请求无效
+func NewInvalid(code, message string) *Error {
+	return &Error{Kind: Invalid, Code: code, Message: message}
+}
+
+// NewUpstream 上游服务失败，err 只写入日志
+func NewUpstream(message string, err error) *Error {
+	return &Error{Kind: Upstream, Code: "upstream_error", Message: message, Err: err}
+}
+
+// Wrap 包装内部错误，message 为展示给用户的提示，为空时使用默认提示；
+// err 已是不需要写入日志的业务错误（资源不存在、请求无效等）时原样返回，
+// 已是上游错误等需要写入日志的业务错误时保留其类型和错误码
+func Wrap(err error, message string) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		if !e.Logged() {
+			return e
+		}
+		if message == "" {
+			message = e.Message
+		}
+		return &Error{Kind: e.Kind, Code: e.Code, Message: message, Err: err}
+	}
+	if message == "" {
+		message = internalMessage
+	}
+	return &Error{Kind: Internal, Code: "internal_error", Message: message, Err: err}
+}
+
+// From 将任意错误转换为业务错误，未分类的错误按内部错误处理；
+// err 链中已有业务错误时使用该错误，内部错误保留完整的 err 以便写入日志
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		if !e.Logged() || e == err {
+			return e
+		}
+		return &Error{Kind: e.Kind, Code: e.Code, Message: e.Message, Err: err}
+	}
+	return Wrap(err, "")
+}
@@ -0,0 +1,38 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("connection reset")
+	notFound := NewNotFound("not_found", "文章不存在")
+
+	tests := []struct {
+		name        string
+		err         error
+		message     string
+		wantKind    Kind
+		wantStatus  int
+		wantMessage string
+	}{
+		{"普通错误按内部错误处理", cause, "获取文章失败", Internal, http.StatusInternalServerError, "获取文章失败"},
+		{"普通错误使用默认提示", cause, "", Internal, http.StatusInternalServerError, internalMessage},
+		{"不需要记录的业务错误原样返回", notFound, "获取文章失败", NotFound, http.StatusNotFound, "文章不存在"},
+		{"上游错误保留类型", NewUpstream("服务商不可用", cause), "翻译失败", Upstream, http.StatusBadGateway, "翻译失败"},
+		{"上游错误使用原提示", NewUpstream("服务商不可用", cause), "", Upstream, http.StatusBadGateway, "服务商不可用"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Wrap(tt.err, tt.message)
+			if e.Kind != tt.wantKind || e.Status() != tt.wantStatus || e.Message != tt.wantMessage {
+				t.Errorf("Wrap = %+v，期望 kind=%d status=%d message=%q", e, tt.wantKind, tt.wantStatus, tt.wantMessage)
+			}
+			if !errors.Is(e, tt.err) && e != tt.err {
+				t.Errorf("Wrap 丢失了原始错误")
+			}
+		})
+	}
+}
@@ -0,0 +1,566 @@
+package controllers
+
+import (
+	"fmt"
+	"go_blog/apperr"
+	"go_blog/embedding"
+	"go_blog/models"
+	"go_blog/search"
+	"go_blog/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// PostForm 后台文章表单，同时用于表单提交和 JSON 请求
+type PostForm struct {
+	Title         string `form:"title" json:"title"`
+	Summary       string `form:"summary" json:"summary"`
+	Category      string `form:"category" json:"category"`
+	PublishTime   string `form:"publishTime" json:"publishTime"`
+	ImageUrl      string `form:"imageUrl" json:"imageUrl"`
+	Content       string `form:"content" json:"content"`
+	ContentFormat string `form:"contentFormat" json:"contentFormat"`
+	Tags          string `form:"tags" json:"tags"` // 以逗号分隔
+}
+
+// 表单校验错误
+var (
+	errInvalidForm        = apperr.NewInvalid("invalid_request", "请求格式错误")
+	errInvalidPublishTime = apperr.NewInvalid("invalid_post", "发布时间格式错误，应为 YYYY-MM-DD")
+)
+
+// 将表单内容写入文章模型
+func (f *PostForm) apply(post *models.Post) error {
+	post.Title = f.Title
+	post.Summary = f.Summary
+	post.Category = f.Category
+	post.ImageUrl = f.ImageUrl
+	post.Content = f.Content
+	post.ContentFormat = f.ContentFormat
+	post.SetTags(models.ParseTags(f.Tags))
+	if f.PublishTime == "" {
+		return models.ErrInvalidPost
+	}
+	t, err := time.ParseInLocation("2006-01-02", f.PublishTime, time.Local)
+	if err != nil {
+		return errInvalidPublishTime
+	}
+	post.PublishTime = t
+	return nil
+}
+
+// 从文章模型生成表单内容
+func newPostForm(post *models.Post) PostForm {
+	return PostForm{
+		Title:         post.Title,
+		Summary:       post.Summary,
+		Category:      post.Category,
+		PublishTime:   post.PublishTime.Format("2006-01-02"),
+		ImageUrl:      post.ImageUrl,
+		Content:       post.Content,
+		ContentFormat: post.ContentFormat,
+		Tags:          strings.Join(post.TagNames(), ", "),
+	}
+}
+
+// AdminRequired 后台页面登录校验中间件，非 GET 请求同时校验表单中的 CSRF Token
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		epoch, err := models.GetSessionEpoch()
+		if err != nil {
+			renderError(c, apperr.Wrap(err, "读取会话失败"))
+			return
+		}
+		session, _ := c.Cookie(utils.SessionCookieName)
+		username, ok := utils.ParseSession(session, epoch)
+		if !ok {
+			c.Redirect(http.StatusFound, "/admin/login")
+			c.Abort()
+			return
+		}
+		if c.Request.Method != http.MethodGet && !utils.CheckCSRFToken(session, c.PostForm("csrf_token")) {
+			renderError(c, errCSRFFailed)
+			return
+		}
+		c.Set("admin", username)
+		c.Set("csrfToken", utils.CSRFToken(session))
+		c.Next()
+	}
+}
+
+// APIAdminRequired 后台接口登录校验中间件，非 GET 请求校验 X-CSRF-Token 请求头
+func APIAdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		epoch, err := models.GetSessionEpoch()
+		if err != nil {
+			renderError(c, apperr.Wrap(err, "读取会话失败"))
+			return
+		}
+		session, _ := c.Cookie(utils.SessionCookieName)
+		username, ok := utils.ParseSession(session, epoch)
+		if !ok {
+			apiError(c, http.StatusUnauthorized, "unauthorized", "请先登录")
+			return
+		}
+		if c.Request.Method != http.MethodGet && !utils.CheckCSRFToken(session, c.GetHeader("X-CSRF-Token")) {
+			apiError(c, http.StatusForbidden, "csrf_failed", "CSRF 校验失败")
+			return
+		}
+		c.Set("admin", username)
+		c.Next()
+	}
+}
+
+// 文章变更后同步搜索索引和文章向量
+func syncPost(id uint) {
+	search.IndexPost(id)
+	embedding.RefreshPost(id)
+}
+
+// 记录后台操作，同时写入数据库和日志
+func audit(c *gin.Context, action string, postID uint, detail string) {
+	entry := &models.AuditLog{
+		Username: c.GetString("admin"),
+		Action:   action,
+		PostID:   postID,
+		Detail:   detail,
+		ClientIP: c.ClientIP(),
+	}
+	fields := logrus.Fields{
+		"admin":     entry.Username,
+		"action":    entry.Action,
+		"post_id":   entry.PostID,
+		"client_ip": entry.ClientIP,
+	}
+	if err := models.CreateAuditLog(entry); err != nil {
+		utils.RequestLog(c).WithFields(fields).WithError(err).Error("写入审计日志失败")
+		return
+	}
+	utils.RequestLog(c).WithFields(fields).Info(detail)
+}
+
+// 登录成功后写入会话 Cookie，返回会话值
+func startSession(c *gin.Context, username string) (string, error) {
+	epoch, err := models.GetSessionEpoch()
+	if err != nil {
+		return "", err
+	}
+	session := utils.NewSession(username, epoch)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(utils.SessionCookieName, session, int(utils.SessionTTL().Seconds()), "/", "", c.Request.TLS != nil, true)
+	return session, nil
+}
+
+// 退出登录：递增会话版本使所有已签发的会话失效，并清除 Cookie
+func endSession(c *gin.Context) error {
+	if err := models.BumpSessionEpoch(); err != nil {
+		return err
+	}
+	c.SetCookie(utils.SessionCookieName, "", -1, "/", "", c.Request.TLS != nil, true)
+	return nil
+}
+
+var errLoginRateLimited = apperr.New(apperr.RateLimited, "rate_limited", "登录尝试过于频繁，请稍后再试")
+
+// AdminLoginPage 登录页面
+func AdminLoginPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "admin_login.html", gin.H{})
+}
+
+// AdminLogin 处理登录表单
+func AdminLogin(c *gin.Context) {
+	username := c.PostForm("username")
+	if !utils.CheckAdminCredentials(username, c.PostForm("password")) {
+		utils.RequestLog(c).WithField("client_ip", c.ClientIP()).Warn("后台登录失败")
+		c.HTML(http.StatusUnauthorized, "admin_login.html", gin.H{
+			"error":    "用户名或密码错误",
+			"username": username,
+		})
+		return
+	}
+	if _, err := startSession(c, username); err != nil {
+		renderError(c, apperr.Wrap(err, "登录失败"))
+		return
+	}
+	c.Set("admin", username)
+	audit(c, "login", 0, "登录后台")
+	c.Redirect(http.StatusFound, "/admin")
+}
+
+// AdminLogout 退出登录
+func AdminLogout(c *gin.Context) {
+	if err := endSession(c); err != nil {
+		renderError(c, apperr.Wrap(err, "退出登录失败"))
+		return
+	}
+	audit(c, "logout", 0, "退出后台")
+	c.Redirect(http.StatusFound, "/admin/login")
+}
+
+// AdminPostList 后台文章列表
+func AdminPostList(c *gin.Context) {
+	page, _ := parsePage(c)
+	pageSize := 20
+
+	posts, total, err := models.AdminGetPosts(page, pageSize)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章列表失败"))
+		return
+	}
+
+	logs, err := models.GetAuditLogs(10)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取操作日志失败"))
+		return
+	}
+
+	c.HTML(http.StatusOK, "admin_posts.html", gin.H{
+		"posts":      posts,
+		"logs":       logs,
+		"page":       page,
+		"totalPages": (int(total) + pageSize - 1) / pageSize,
+		"totalPosts": total,
+		"admin":      c.GetString("admin"),
+		"csrfToken":  c.GetString("csrfToken"),
+	})
+}
+
+// AdminNewPost 新建文章页面
+func AdminNewPost(c *gin.Context) {
+	c.HTML(http.StatusOK, "admin_edit.html", gin.H{
+		"form":      PostForm{PublishTime: time.Now().Format("2006-01-02")},
+		"action":    "/admin/posts",
+		"csrfToken": c.GetString("csrfToken"),
+	})
+}
+
+// AdminCreatePost 处理新建文章表单
+func AdminCreatePost(c *gin.Context) {
+	var form PostForm
+	if err := c.ShouldBind(&form); err != nil {
+		renderPostForm(c, form, "/admin/posts", errInvalidForm)
+		return
+	}
+
+	var post models.Post
+	if err := form.apply(&post); err != nil {
+		renderPostForm(c, form, "/admin/posts", err)
+		return
+	}
+	if err := models.CreatePost(&post); err != nil {
+		renderPostForm(c, form, "/admin/posts", apperr.Wrap(err, "创建文章失败"))
+		return
+	}
+
+	syncPost(post.ID)
+	audit(c, "create", post.ID, "创建文章: "+post.Title)
+	c.Redirect(http.StatusFound, "/admin")
+}
+
+// AdminEditPost 编辑文章页面
+func AdminEditPost(c *gin.Context) {
+	post, ok := adminLoadPost(c)
+	if !ok {
+		return
+	}
+	c.HTML(http.StatusOK, "admin_edit.html", gin.H{
+		"post":      post,
+		"form":      newPostForm(post),
+		"action":    fmt.Sprintf("/admin/posts/%d", post.ID),
+		"csrfToken": c.GetString("csrfToken"),
+	})
+}
+
+// AdminUpdatePost 处理编辑文章表单
+func AdminUpdatePost(c *gin.Context) {
+	post, ok := adminLoadPost(c)
+	if !ok {
+		return
+	}
+	action := fmt.Sprintf("/admin/posts/%d", post.ID)
+
+	var form PostForm
+	if err := c.ShouldBind(&form); err != nil {
+		renderPostForm(c, form, action, errInvalidForm)
+		return
+	}
+	if err := form.apply(post); err != nil {
+		renderPostForm(c, form, action, err)
+		return
+	}
+	if err := models.UpdatePost(post); err != nil {
+		renderPostForm(c, form, action, apperr.Wrap(err, "更新文章失败"))
+		return
+	}
+
+	syncPost(post.ID)
+	audit(c, "update", post.ID, "编辑文章: "+post.Title)
+	c.Redirect(http.StatusFound, "/admin")
+}
+
+// AdminSetPostStatus 发布或下线文章
+func AdminSetPostStatus(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, ok := adminLoadPost(c)
+		if !ok {
+			return
+		}
+		if err := models.SetPostStatus(int(post.ID), status); err != nil {
+			renderError(c, apperr.Wrap(err, "修改文章状态失败"))
+			return
+		}
+		syncPost(post.ID)
+		audit(c, statusAction(status), post.ID, "修改文章状态: "+post.Title)
+		c.Redirect(http.StatusFound, "/admin")
+	}
+}
+
+// AdminDeletePost 删除文章
+func AdminDeletePost(c *gin.Context) {
+	post, ok := adminLoadPost(c)
+	if !ok {
+		return
+	}
+	if err := models.DeletePost(int(post.ID)); err != nil {
+		renderError(c, apperr.Wrap(err, "删除文章失败"))
+		return
+	}
+	syncPost(post.ID)
+	audit(c, "delete", post.ID, "删除文章: "+post.Title)
+	c.Redirect(http.StatusFound, "/admin")
+}
+
+// AdminClearSummary 清除文章的 AI 摘要缓存，下次访问时重新生成
+func AdminClearSummary(c *gin.Context) {
+	post, ok := adminLoadPost(c)
+	if !ok {
+		return
+	}
+	if err := models.DeletePostSummaries(post.ID); err != nil {
+		renderError(c, apperr.Wrap(err, "清除摘要缓存失败"))
+		return
+	}
+	audit(c, "summary", post.ID, "清除摘要缓存: "+post.Title)
+	c.Redirect(http.StatusFound, "/admin")
+}
+
+// 根据路由参数加载文章，失败时直接输出错误页面
+func adminLoadPost(c *gin.Context) (*models.Post, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderError(c, errInvalidPostID)
+		return nil, false
+	}
+	post, err := models.AdminGetPost(id)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章失败"))
+		return nil, false
+	}
+	return post, true
+}
+
+// 保存失败时重新渲染编辑页面，内部错误只展示提示
+func renderPostForm(c *gin.Context, form PostForm, action string, err error) {
+	e := apperr.Wrap(err, "保存文章失败")
+	logError(c, e)
+	c.HTML(e.Status(), "admin_edit.html", gin.H{
+		"form":      form,
+		"action":    action,
+		"error":     e.Message,
+		"csrfToken": c.GetString("csrfToken"),
+	})
+}
+
+func statusAction(status string) string {
+	if status == models.PostStatusPublished {
+		return "publish"
+	}
+	return "unpublish"
+}
+
+// APIAdminLogin 接口登录，返回后续请求需要携带的 CSRF Token
+func APIAdminLogin(c *gin.Context) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", "请求格式错误")
+		return
+	}
+	if !utils.CheckAdminCredentials(req.Username, req.Password) {
+		utils.RequestLog(c).WithField("client_ip", c.ClientIP()).Warn("后台登录失败")
+		apiError(c, http.StatusUnauthorized, "unauthorized", "用户名或密码错误")
+		return
+	}
+	session, err := startSession(c, req.Username)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "登录失败"))
+		return
+	}
+	c.Set("admin", req.Username)
+	audit(c, "login", 0, "接口登录后台")
+	c.JSON(http.StatusOK, gin.H{
+		"csrfToken": utils.CSRFToken(session),
+	})
+}
+
+// APIAdminLogout 接口退出登录，所有已签发的会话随之失效
+func APIAdminLogout(c *gin.Context) {
+	if err := endSession(c); err != nil {
+		renderError(c, apperr.Wrap(err, "退出登录失败"))
+		return
+	}
+	audit(c, "logout", 0, "接口退出后台")
+	c.Status(http.StatusNoContent)
+}
+
+// APIAdminPostList 后台接口获取文章列表，包含未发布的文章
+func APIAdminPostList(c *gin.Context) {
+	page, _ := parsePage(c)
+	pageSize := 20
+
+	posts, total, err := models.AdminGetPosts(page, pageSize)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章列表失败"))
+		return
+	}
+
+	items := make([]gin.H, 0, len(posts))
+	for i := range posts {
+		items = append(items, gin.H{
+			"post":   newAPIPost(&posts[i], false),
+			"status": posts[i].Status,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":      items,
+		"page":       page,
+		"pageSize":   pageSize,
+		"total":      total,
+		"totalPages": (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+// APIAdminCreatePost 接口创建文章
+func APIAdminCreatePost(c *gin.Context) {
+	var form PostForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", "请求格式错误")
+		return
+	}
+
+	var post models.Post
+	if err := form.apply(&post); err != nil {
+		renderError(c, err)
+		return
+	}
+	if err := models.CreatePost(&post); err != nil {
+		renderError(c, apperr.Wrap(err, "创建文章失败"))
+		return
+	}
+
+	syncPost(post.ID)
+	audit(c, "create", post.ID, "创建文章: "+post.Title)
+	c.JSON(http.StatusCreated, gin.H{
+		"post": newAPIPost(&post, false),
+	})
+}
+
+// APIAdminUpdatePost 接口编辑文章
+func APIAdminUpdatePost(c *gin.Context) {
+	post, ok := apiAdminLoadPost(c)
+	if !ok {
+		return
+	}
+
+	var form PostForm
+	if err := c.ShouldBindJSON(&form); err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", "请求格式错误")
+		return
+	}
+	if err := form.apply(post); err != nil {
+		renderError(c, err)
+		return
+	}
+	if err := models.UpdatePost(post); err != nil {
+		renderError(c, apperr.Wrap(err, "更新文章失败"))
+		return
+	}
+
+	syncPost(post.ID)
+	audit(c, "update", post.ID, "编辑文章: "+post.Title)
+	c.JSON(http.StatusOK, gin.H{
+		"post": newAPIPost(post, false),
+	})
+}
+
+// APIAdminSetPostStatus 接口发布或下线文章
+func APIAdminSetPostStatus(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		post, ok := apiAdminLoadPost(c)
+		if !ok {
+			return
+		}
+		if err := models.SetPostStatus(int(post.ID), status); err != nil {
+			renderError(c, apperr.Wrap(err, "修改文章状态失败"))
+			return
+		}
+		syncPost(post.ID)
+		audit(c, statusAction(status), post.ID, "修改文章状态: "+post.Title)
+		c.JSON(http.StatusOK, gin.H{
+			"id":     post.ID,
+			"status": status,
+		})
+	}
+}
+
+// APIAdminDeletePost 接口删除文章
+func APIAdminDeletePost(c *gin.Context) {
+	post, ok := apiAdminLoadPost(c)
+	if !ok {
+		return
+	}
+	if err := models.DeletePost(int(post.ID)); err != nil {
+		renderError(c, apperr.Wrap(err, "删除文章失败"))
+		return
+	}
+	syncPost(post.ID)
+	audit(c, "delete", post.ID, "删除文章: "+post.Title)
+	c.Status(http.StatusNoContent)
+}
+
+// APIAdminClearSummary 接口清除文章的 AI 摘要缓存
+func APIAdminClearSummary(c *gin.Context) {
+	post, ok := apiAdminLoadPost(c)
+	if !ok {
+		return
+	}
+	if err := models.DeletePostSummaries(post.ID); err != nil {
+		renderError(c, apperr.Wrap(err, "清除摘要缓存失败"))
+		return
+	}
+	audit(c, "summary", post.ID, "清除摘要缓存: "+post.Title)
+	c.Status(http.StatusNoContent)
+}
+
+// 根据路由参数加载文章，失败时直接输出 JSON 错误
+func apiAdminLoadPost(c *gin.Context) (*models.Post, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_id", "无效的文章ID")
+		return nil, false
+	}
+	post, err := models.AdminGetPost(id)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章失败"))
+		return nil, false
+	}
+	return post, true
+}
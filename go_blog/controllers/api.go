@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"errors"
+	"go_blog/apperr"
+	"go_blog/models"
+	"go_blog/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIPost 接口返回的文章结构
+type APIPost struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	Summary     string    `json:"summary"`
+	Category    string    `json:"category"`
+	PublishTime time.Time `json:"publishTime"`
+	ImageUrl    string    `json:"imageUrl"`
+	Tags        []string  `json:"tags"`
+	Words       int       `json:"words"`
+	ReadingTime int       `json:"readingTime"`
+	Content     string    `json:"content,omitempty"`
+	Lang        string    `json:"lang,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// APIError 统一的错误响应结构
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// 将文章模型转换为接口结构，withContent 为 true 时附带正文
+func newAPIPost(post *models.Post, withContent bool) APIPost {
+	p := APIPost{
+		ID:          post.ID,
+		Title:       post.Title,
+		Summary:     post.Summary,
+		Category:    post.Category,
+		PublishTime: post.PublishTime,
+		ImageUrl:    post.ImageUrl,
+		Tags:        post.TagNames(),
+		Words:       post.Words(),
+		ReadingTime: post.ReadingTime,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+	}
+	if withContent {
+		p.Content = string(post.DisplayHTML)
+	}
+	return p
+}
+
+// 输出统一格式的错误响应
+func apiError(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error": APIError{Code: code, Message: message, RequestID: utils.GetRequestID(c)},
+	})
+}
+
+// APIPostList 获取文章列表，支持页码分页和游标分页（cursor 参数）
+func APIPostList(c *gin.Context) {
+	pageSize, ok := parsePageSize(c)
+	if !ok {
+		apiError(c, http.StatusBadRequest, "invalid_page_size", "无效的每页数量")
+		return
+	}
+	sort := c.DefaultQuery("sort", models.SortNewest)
+	if !models.ValidSort(sort) {
+		apiError(c, http.StatusBadRequest, "invalid_sort", "排序方式只能是 newest、oldest、title 或 updated")
+		return
+	}
+	filter := models.PostFilter{
+		Category: c.Query("category"),
+		Tags:     queryTags(c),
+	}
+
+	// 游标分页：从上一页返回的 nextCursor 之后继续，不计算总数
+	if raw, ok := c.GetQuery("cursor"); ok {
+		var cursor *models.Cursor
+		if raw != "" {
+			var err error
+			if cursor, err = models.DecodeCursor(raw); err != nil {
+				apiError(c, http.StatusBadRequest, "invalid_cursor", err.Error())
+				return
+			}
+		}
+		posts, next, err := models.GetPostsAfter(cursor, pageSize, filter, sort)
+		if errors.Is(err, models.ErrInvalidCursor) {
+			apiError(c, http.StatusBadRequest, "invalid_cursor", err.Error())
+			return
+		}
+		if err != nil {
+			renderError(c, apperr.Wrap(err, "获取文章列表失败"))
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"posts":      apiPostList(posts),
+			"pageSize":   pageSize,
+			"sort":       sort,
+			"nextCursor": encodeCursor(next),
+		})
+		return
+	}
+
+	page, ok := parsePage(c)
+	if !ok {
+		apiError(c, http.StatusBadRequest, "invalid_page", "页码必须是大于 0 的整数")
+		return
+	}
+	posts, total, err := models.GetPosts(page, pageSize, filter, sort)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章列表失败"))
+		return
+	}
+
+	// 页码分页同样返回游标，便于客户端切换到游标分页
+	var next *models.Cursor
+	if len(posts) > 0 && int64(page*pageSize) < total {
+		next = models.NewCursor(sort, &posts[len(posts)-1])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":      apiPostList(posts),
+		"page":       page,
+		"pageSize":   pageSize,
+		"sort":       sort,
+		"total":      total,
+		"totalPages": (int(total) + pageSize - 1) / pageSize,
+		"nextCursor": encodeCursor(next),
+	})
+}
+
+func apiPostList(posts []models.Post) []APIPost {
+	items := make([]APIPost, 0, len(posts))
+	for i := range posts {
+		items = append(items, newAPIPost(&posts[i], false))
+	}
+	return items
+}
+
+// 没有下一页时返回空字符串
+func encodeCursor(cursor *models.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	return cursor.Encode()
+}
+
+// APIPostDetail 获取文章详情
+func APIPostDetail(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_id", "无效的文章ID")
+		return
+	}
+
+	post, err := models.GetPostByID(id)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章失败"))
+		return
+	}
+
+	// 请求了其他语言时返回译文
+	lang := c.Query("lang")
+	if lang != "" {
+		translation, err := translatePost(c, post, lang)
+		if err != nil {
+			renderError(c, err)
+			return
+		}
+		post.ApplyTranslation(translation)
+		lang = translation.Lang
+	}
+
+	item := newAPIPost(post, true)
+	item.Lang = lang
+	c.JSON(http.StatusOK, gin.H{
+		"post": item,
+	})
+}
+
+// APICategoryList 获取所有分类
+func APICategoryList(c *gin.Context) {
+	categories, err := models.GetCategories()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取分类失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": categories,
+	})
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"go_blog/apperr"
+	"go_blog/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Archive 按年月归档页面
+func Archive(c *gin.Context) {
+	years, err := models.GetArchive()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取归档失败"))
+		return
+	}
+
+	c.HTML(http.StatusOK, "archive.html", gin.H{
+		"years": years,
+	})
+}
+
+// ArchiveList 某年或某月发布的文章列表
+func ArchiveList(c *gin.Context) {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < 1 || year > 9999 {
+		renderError(c, apperr.NewNotFound("not_found", "无效的年份"))
+		return
+	}
+	month := 0
+	if m := c.Param("month"); m != "" {
+		month, err = strconv.Atoi(m)
+		if err != nil || month < 1 || month > 12 {
+			renderError(c, apperr.NewNotFound("not_found", "无效的月份"))
+			return
+		}
+	}
+
+	// 发布时间范围，按月份或整年
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	before := from.AddDate(1, 0, 0)
+	if month > 0 {
+		from = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+		before = from.AddDate(0, 1, 0)
+	}
+
+	page, _ := parsePage(c)
+	pageSize, _ := parsePageSize(c)
+	sort := c.Query("sort")
+	if !models.ValidSort(sort) {
+		sort = ""
+	}
+
+	posts, total, err := models.GetPosts(page, pageSize, models.PostFilter{
+		PublishedFrom:   from,
+		PublishedBefore: before,
+	}, sort)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章列表失败"))
+		return
+	}
+	totalPages := (int(total) + pageSize - 1) / pageSize
+	if redirectToLastPage(c, page, totalPages) {
+		return
+	}
+
+	years, err := models.GetArchive()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取归档失败"))
+		return
+	}
+
+	c.HTML(http.StatusOK, "archive.html", gin.H{
+		"years":      years,
+		"year":       year,
+		"month":      month,
+		"posts":      posts,
+		"page":       page,
+		"totalPages": totalPages,
+		"totalPosts": total,
+		"pageQuery":  listPageQuery(c),
+	})
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"fmt"
+	"go_blog/apperr"
+	"go_blog/llm"
+	"go_blog/models"
+	"go_blog/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChatRequest 读者的提问
+type ChatRequest struct {
+	Message string `json:"message" form:"message"`
+}
+
+// 获取读者的问答会话ID，没有时创建并写入 Cookie
+func chatSession(c *gin.Context) string {
+	value, _ := c.Cookie(utils.ChatCookieName)
+	if id, ok := utils.ParseChatSession(value); ok {
+		return id
+	}
+	value = utils.NewChatSession()
+	id, _ := utils.ParseChatSession(value)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(utils.ChatCookieName, value, 0, "/", "", c.Request.TLS != nil, true)
+	return id
+}
+
+// 对话历史的键，每个会话的每篇文章各自独立
+func chatKey(session string, postID uint) string {
+	return fmt.Sprintf("%s:%d", session, postID)
+}
+
+var errEmptyQuestion = apperr.NewInvalid("empty_question", "问题不能为空")
+
+// PostChat 针对文章内容回答读者的问题，以 SSE 流式返回
+func PostChat(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderError(c, errInvalidPostID)
+		return
+	}
+
+	post, err := models.GetPostByID(id)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取文章失败"))
+		return
+	}
+	log := utils.RequestLog(c).WithField("post_id", post.ID)
+
+	settings := llm.GetChatSettings()
+	var req ChatRequest
+	if err := c.ShouldBind(&req); err != nil {
+		renderError(c, errInvalidForm)
+		return
+	}
+	question := strings.TrimSpace(req.Message)
+	if question == "" {
+		renderError(c, errEmptyQuestion)
+		return
+	}
+	if utf8.RuneCountInString(question) > settings.MaxQuestionLength {
+		renderError(c, apperr.NewInvalid("question_too_long", fmt.Sprintf("问题不能超过 %d 个字", settings.MaxQuestionLength)))
+		return
+	}
+
+	if err := llm.CheckBudget(); err != nil {
+		renderError(c, apperr.Wrap(err, "回答问题失败"))
+		return
+	}
+
+	// 调用服务商前预留提问次数，失败的提问归还次数
+	key := chatKey(chatSession(c), post.ID)
+	history, turns, ok := llm.Chats.Reserve(key, settings.MaxTurns)
+	if !ok {
+		renderError(c, llm.ErrTooManyTurns)
+		return
+	}
+	answered := false
+	defer func() {
+		if !answered {
+			llm.Chats.Release(key)
+		}
+	}()
+
+	// 使用 SSE 流式输出，此后无法再修改状态码，错误通过 error 事件返回
+	stream := newSSEStream(c)
+	defer stream.Close()
+
+	// 上游请求与客户端连接绑定，读者离开后立即停止生成
+	ctx := c.Request.Context()
+	var answer strings.Builder
+	usage, err := llm.Chat(ctx, llm.WithUsage(llm.Default, "chat", post.ID), post.Title,
+		utils.ExtractBlocks(post.SourceHTML()), history, question,
+		func(delta string) error {
+			answer.WriteString(delta)
+			return stream.Send(eventDelta, gin.H{"text": delta})
+		})
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Info("客户端已断开，停止回答问题")
+			return
+		}
+		log.WithError(err).Error("回答问题失败")
+		stream.Send(eventError, gin.H{"message": "回答问题失败"})
+		return
+	}
+
+	// 只记录完整的问答，失败的提问不计入次数
+	if answer.Len() > 0 {
+		llm.Chats.Append(key, question, answer.String())
+		answered = true
+	} else {
+		turns--
+	}
+
+	stream.Send(eventUsage, usage)
+	stream.Send(eventDone, gin.H{"turns": turns, "remaining": settings.MaxTurns - turns})
+}
+
+// ResetPostChat 清空读者在这篇文章下的对话
+func ResetPostChat(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderError(c, errInvalidPostID)
+		return
+	}
+	llm.Chats.Reset(chatKey(chatSession(c), uint(id)))
+	c.Status(http.StatusNoContent)
+}
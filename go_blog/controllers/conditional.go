@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go_blog/models"
+	"go_blog/utils"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 设置 ETag 和 Last-Modified 响应头，客户端缓存仍然有效时返回 304 并返回 true
+func checkNotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	// If-None-Match 优先于 If-Modified-Since
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if etagMatches(match, etag) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+	if since := c.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err == nil && !lastModified.After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// 判断 If-None-Match 中是否包含当前 ETag（弱比较）
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// 服务启动时间，作为主题样式表和页面的 Last-Modified 下限
+var startedAt = time.Now()
+
+// Cache-Control 的默认值，配置为空时使用：页面每次都向服务器校验，订阅源缓存 10 分钟
+const (
+	defaultPageCacheControl = "public, no-cache"
+	defaultFeedCacheControl = "public, max-age=600"
+)
+
+// 页面的 Cache-Control
+func pageCacheControl() string {
+	if v := utils.AppConfig.Cache.Pages; v != "" {
+		return v
+	}
+	return defaultPageCacheControl
+}
+
+// 订阅源的 Cache-Control
+func feedCacheControl() string {
+	if v := utils.AppConfig.Cache.Feeds; v != "" {
+		return v
+	}
+	return defaultFeedCacheControl
+}
+
+// 响应的校验信息：按顺序写入影响响应内容的数据，ETag 取其哈希，Last-Modified 取其中最晚的更新时间。
+// 请求地址和服务启动时间也计入其中，重启后模板或配置变化时客户端会重新获取
+type validator struct {
+	h            hash.Hash
+	lastModified time.Time
+}
+
+func newValidator(c *gin.Context) *validator {
+	v := &validator{h: sha1.New(), lastModified: startedAt}
+	fmt.Fprintf(v.h, "%s|%d|", c.Request.URL.RequestURI(), startedAt.UnixNano())
+	return v
+}
+
+// 写入任意影响响应内容的数据
+func (v *validator) add(format string, args ...interface{}) {
+	fmt.Fprintf(v.h, format+"|", args...)
+}
+
+// 写入文章的 ID、更新时间和标签，重命名标签不会修改文章的更新时间
+func (v *validator) addPosts(posts ...models.Post) {
+	for i := range posts {
+		v.addTime(posts[i].UpdatedAt)
+		fmt.Fprintf(v.h, "%d:%d:%v|", posts[i].ID, posts[i].UpdatedAt.UnixNano(), posts[i].TagNames())
+	}
+}
+
+// 更新 Last-Modified
+func (v *validator) addTime(t time.Time) {
+	if t.After(v.lastModified) {
+		v.lastModified = t
+	}
+}
+
+func (v *validator) etag() string {
+	return `"` + hex.EncodeToString(v.h.Sum(nil)) + `"`
+}
+
+// 设置 Cache-Control、ETag 和 Last-Modified，客户端缓存仍然有效时返回 304 并返回 true
+func (v *validator) notModified(c *gin.Context, cacheControl string) bool {
+	c.Header("Cache-Control", cacheControl)
+	return checkNotModified(c, v.etag(), v.lastModified)
+}
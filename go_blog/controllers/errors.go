@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"go_blog/apperr"
+	"go_blog/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 常用的业务错误
+var (
+	errPageNotFound  = apperr.NewNotFound("not_found", "页面不存在")
+	errInvalidPostID = apperr.NewInvalid("invalid_id", "无效的文章ID")
+	errCSRFFailed    = apperr.New(apperr.Forbidden, "csrf_failed", "CSRF 校验失败，请刷新页面后重试")
+	errUnknownStyle  = apperr.NewInvalid("unknown_style", "未知的摘要风格")
+)
+
+// NoRoute 未匹配任何路由时返回 404
+func NoRoute(c *gin.Context) {
+	renderError(c, errPageNotFound)
+}
+
+// 按错误类型输出错误：接口请求和页面脚本发起的请求返回 JSON，页面请求渲染 error.html。
+// 内部错误和上游错误的原因只写入带请求 ID 的日志，客户端只能看到提示和请求 ID
+func renderError(c *gin.Context, err error) {
+	e := apperr.From(err)
+	logError(c, e)
+
+	status := e.Status()
+	if wantsJSON(c) {
+		apiError(c, status, e.Code, e.Message)
+		return
+	}
+	c.HTML(status, "error.html", gin.H{
+		"status":    status,
+		"error":     e.Message,
+		"requestID": utils.GetRequestID(c),
+	})
+	c.Abort()
+}
+
+// 记录内部错误和上游错误的原因
+func logError(c *gin.Context, e *apperr.Error) {
+	if !e.Logged() {
+		return
+	}
+	entry := utils.RequestLog(c).WithField("req_uri", c.Request.RequestURI)
+	if e.Err != nil {
+		entry = entry.WithError(e.Err)
+	}
+	entry.Error(e.Message)
+}
+
+// 是否以 JSON 返回错误：接口请求，以及明确不接受 HTML 的请求（如页面脚本发起的 SSE 请求）
+func wantsJSON(c *gin.Context) bool {
+	if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		return true
+	}
+	accept := c.GetHeader("Accept")
+	return accept != "" && !strings.Contains(accept, "text/html") && !strings.Contains(accept, "*/*")
+}
@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go_blog/apperr"
+	"go_blog/models"
+	"go_blog/utils"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 订阅源标题
+	feedTitle = "博客demo"
+	// 订阅源包含的文章数
+	feedSize = 20
+)
+
+// RSS 2.0 结构
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description"`
+	Content     rssCDATA      `xml:"content:encoded"`
+	Category    string        `xml:"category,omitempty"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// Atom 结构
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Links     []atomLink `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Category  *atomTerm  `xml:"category"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// 获取站点根地址，优先使用配置
+func siteURL(c *gin.Context) string {
+	if base := utils.AppConfig.Server.BaseURL; base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// 加载订阅源文章，并处理缓存校验；返回 false 表示已经输出响应
+func loadFeedPosts(c *gin.Context) ([]models.Post, time.Time, bool) {
+	category := c.Param("category")
+	posts, err := models.GetFeedPosts(category, feedSize)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取订阅源失败"))
+		return nil, time.Time{}, false
+	}
+
+	// 以文章ID和更新时间计算 ETag
+	var updated time.Time
+	for _, p := range posts {
+		if p.UpdatedAt.After(updated) {
+			updated = p.UpdatedAt
+		}
+	}
+	v := newValidator(c)
+	v.addPosts(posts...)
+	if v.notModified(c, feedCacheControl()) {
+		return nil, updated, false
+	}
+	return posts, updated, true
+}
+
+// 生成文章图片的附件信息
+func imageEnclosure(imageURL string) *rssEnclosure {
+	if imageURL == "" {
+		return nil
+	}
+	typ := mime.TypeByExtension(path.Ext(strings.SplitN(imageURL, "?", 2)[0]))
+	if !strings.HasPrefix(typ, "image/") {
+		typ = "image/jpeg"
+	}
+	return &rssEnclosure{URL: imageURL, Type: typ}
+}
+
+// 订阅源标题，分类订阅附带分类名
+func feedTitleFor(category string) string {
+	if category == "" {
+		return feedTitle
+	}
+	return feedTitle + " - " + category
+}
+
+// RSSFeed 输出 RSS 2.0 订阅源
+func RSSFeed(c *gin.Context) {
+	posts, updated, ok := loadFeedPosts(c)
+	if !ok {
+		return
+	}
+
+	base := siteURL(c)
+	category := c.Param("category")
+	channel := rssChannel{
+		Title:       feedTitleFor(category),
+		Link:        base + "/",
+		Description: feedTitleFor(category),
+		AtomLink:    atomLink{Href: base + c.Request.URL.Path, Rel: "self", Type: "application/rss+xml"},
+	}
+	if category != "" {
+		channel.Link = base + "/category/" + url.PathEscape(category)
+	}
+	if !updated.IsZero() {
+		channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+
+	for _, p := range posts {
+		link := fmt.Sprintf("%s/post/%d", base, p.ID)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       p.Title,
+			Link:        link,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			Description: p.Summary,
+			Content:     rssCDATA{Value: string(p.DisplayHTML)},
+			Category:    p.Category,
+			PubDate:     p.PublishTime.Format(time.RFC1123Z),
+			Enclosure:   imageEnclosure(p.ImageUrl),
+		})
+	}
+
+	writeXML(c, "application/rss+xml; charset=utf-8", rssFeed{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Channel:   channel,
+	})
+}
+
+// AtomFeed 输出 Atom 订阅源
+func AtomFeed(c *gin.Context) {
+	posts, updated, ok := loadFeedPosts(c)
+	if !ok {
+		return
+	}
+
+	base := siteURL(c)
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed := atomFeed{
+		Title: feedTitle,
+		ID:    base + "/",
+		Links: []atomLink{
+			{Href: base + "/", Rel: "alternate", Type: "text/html"},
+			{Href: base + c.Request.URL.Path, Rel: "self", Type: "application/atom+xml"},
+		},
+		Updated: updated.Format(time.RFC3339),
+	}
+
+	for _, p := range posts {
+		link := fmt.Sprintf("%s/post/%d", base, p.ID)
+		entry := atomEntry{
+			Title:     p.Title,
+			ID:        link,
+			Links:     []atomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Published: p.PublishTime.Format(time.RFC3339),
+			Updated:   p.UpdatedAt.Format(time.RFC3339),
+			Summary:   atomText{Type: "text", Value: p.Summary},
+			Content:   atomText{Type: "html", Value: string(p.DisplayHTML)},
+		}
+		if p.Category != "" {
+			entry.Category = &atomTerm{Term: p.Category}
+		}
+		if enclosure := imageEnclosure(p.ImageUrl); enclosure != nil {
+			entry.Links = append(entry.Links, atomLink{Href: enclosure.URL, Rel: "enclosure", Type: enclosure.Type})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	writeXML(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+// 输出带 XML 声明的响应
+func writeXML(c *gin.Context, contentType string, v interface{}) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "生成订阅源失败"))
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"go_blog/apperr"
+	"go_blog/transform"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HighlightCSS 输出代码高亮主题的样式表
+func HighlightCSS(c *gin.Context) {
+	css, err := transform.HighlightCSS()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "生成代码高亮样式失败"))
+		return
+	}
+
+	sum := sha1.Sum([]byte(css))
+	c.Header("Cache-Control", "public, max-age=86400")
+	if checkNotModified(c, `"`+hex.EncodeToString(sum[:])+`"`, startedAt) {
+		return
+	}
+	c.Data(http.StatusOK, "text/css; charset=utf-8", []byte(css))
+}
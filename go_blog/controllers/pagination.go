@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"go_blog/utils"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 每页数量的默认值和上限，配置为 0 时使用
+const (
+	defaultPageSize    = 10
+	defaultMaxPageSize = 50
+)
+
+// 解析页码，未指定时为 1；格式错误或小于 1 时返回 1 和 false。
+// 页码不超过 math.MaxInt32 / 每页数量上限，避免计算偏移量时溢出，更大的页码取上限
+func parsePage(c *gin.Context) (int, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return 1, false
+	}
+	if max := math.MaxInt32 / maxPageSize(); page > max {
+		page = max
+	}
+	return page, true
+}
+
+// 每页数量的上限
+func maxPageSize() int {
+	if max := utils.AppConfig.Pagination.MaxPageSize; max > 0 {
+		return max
+	}
+	return defaultMaxPageSize
+}
+
+// 解析每页数量，未指定 page_size 时使用配置的默认值，超过上限时取上限；
+// 格式错误或小于 1 时返回默认值和 false
+func parsePageSize(c *gin.Context) (int, bool) {
+	cfg := utils.AppConfig.Pagination
+	max := maxPageSize()
+	size := cfg.PageSize
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	if size > max {
+		size = max
+	}
+
+	if v := c.Query("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return size, false
+		}
+		size = n
+		if size > max {
+			size = max
+		}
+	}
+	return size, true
+}
+
+// 页码超过总页数时跳转到最后一页，保留其他查询参数，已跳转时返回 true
+func redirectToLastPage(c *gin.Context, page, totalPages int) bool {
+	if totalPages < 1 || page <= totalPages {
+		return false
+	}
+	query := c.Request.URL.Query()
+	query.Set("page", strconv.Itoa(totalPages))
+	c.Redirect(http.StatusFound, c.Request.URL.Path+"?"+query.Encode())
+	return true
+}
+
+// 文章列表的分页链接需要保留的查询参数，格式同 pagination 模板中的 pageQuery
+func listPageQuery(c *gin.Context) template.URL {
+	query := url.Values{}
+	for _, key := range []string{"sort", "page_size"} {
+		if v := c.Query(key); v != "" {
+			query.Set(key, v)
+		}
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return template.URL(query.Encode() + "&")
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParsePage(t *testing.T) {
+	maxPage := math.MaxInt32 / defaultMaxPageSize
+	tests := []struct {
+		name   string
+		query  string
+		want   int
+		wantOK bool
+	}{
+		{"未指定", "", 1, true},
+		{"正常页码", "page=3", 3, true},
+		{"格式错误", "page=abc", 1, false},
+		{"小于 1", "page=0", 1, false},
+		{"负数", "page=-2", 1, false},
+		{"超过上限", "page=" + strconv.Itoa(maxPage+1), maxPage, true},
+		{"接近整数上限", "page=" + strconv.Itoa(math.MaxInt), maxPage, true},
+		{"超出整数范围", "page=99999999999999999999", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			page, ok := parsePage(c)
+			if page != tt.want || ok != tt.wantOK {
+				t.Fatalf("parsePage(%q) = %d, %v，期望 %d, %v", tt.query, page, ok, tt.want, tt.wantOK)
+			}
+			// 按每页数量上限计算偏移量不会溢出
+			if offset := (page - 1) * defaultMaxPageSize; offset < 0 || offset > math.MaxInt32 {
+				t.Errorf("偏移量 = %d 溢出", offset)
+			}
+		})
+	}
+}
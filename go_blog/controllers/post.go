@@ -1,50 +1,73 @@
 package controllers
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
+	"errors"
+	"go_blog/apperr"
+	"go_blog/embedding"
+	"go_blog/llm"
 	"go_blog/models"
 	"go_blog/utils"
-	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/net/html"
+	"github.com/sirupsen/logrus"
 )
 
 func PostList(c *gin.Context) {
-	// 获取分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize := 10
+	// 获取分页参数，无效的页码按第一页处理
+	page, _ := parsePage(c)
+	pageSize, _ := parsePageSize(c)
+	sort := c.Query("sort")
+	if !models.ValidSort(sort) {
+		sort = ""
+	}
 
-	// 获取分类
+	// 获取分类和标签
 	category := c.Param("category")
+	tag := models.NormalizeTag(c.Param("tag"))
+	filter := models.PostFilter{Category: category}
+	if tag != "" {
+		filter.Tags = []string{tag}
+	}
 
 	// 获取文章列表
-	posts, total, err := models.GetPosts(page, pageSize, category)
+	posts, total, err := models.GetPosts(page, pageSize, filter, sort)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"error": err.Error(),
-		})
+		renderError(c, apperr.Wrap(err, "获取文章列表失败"))
+		return
+	}
+
+	// 计算总页数，页码超出时跳转到最后一页
+	totalPages := (int(total) + pageSize - 1) / pageSize
+	if redirectToLastPage(c, page, totalPages) {
 		return
 	}
 
 	// 获取所有分类
 	categories, err := models.GetCategories()
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"error": err.Error(),
-		})
+		renderError(c, apperr.Wrap(err, "获取分类失败"))
 		return
 	}
 
-	// 计算总页数
-	totalPages := (int(total) + pageSize - 1) / pageSize
+	// 获取标签云
+	tags, err := models.GetTagCounts(tagCloudSize)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取标签失败"))
+		return
+	}
+
+	// 列表、分类和标签云都没有变化时返回 304
+	v := newValidator(c)
+	v.addPosts(posts...)
+	v.add("%d|%v|%v", total, categories, tags)
+	if v.notModified(c, pageCacheControl()) {
+		return
+	}
 
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"posts":      posts,
@@ -52,7 +75,11 @@ func PostList(c *gin.Context) {
 		"totalPages": totalPages,
 		"category":   category,
 		"categories": categories,
+		"tag":        tag,
+		"tags":       tagCloud(tags),
+		"sort":       sort,
 		"totalPosts": total,
+		"pageQuery":  listPageQuery(c),
 	})
 }
 
@@ -60,174 +87,215 @@ func PostDetail(c *gin.Context) {
 	// 获取文章ID
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.HTML(http.StatusBadRequest, "error.html", gin.H{
-			"error": "无效的文章ID",
-		})
+		renderError(c, errInvalidPostID)
 		return
 	}
 
 	// 获取文章详情
 	post, err := models.GetPostByID(id)
 	if err != nil {
-		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
-			"error": err.Error(),
-		})
+		renderError(c, apperr.Wrap(err, "获取文章失败"))
 		return
 	}
 
 	// // 将内容转换为 template.HTML 类型
 	// post.Content = template.HTML(post.Content)
 
+	v := newValidator(c)
+	v.addPosts(*post)
+
+	// 请求了其他语言时展示译文
+	lang := c.Query("lang")
+	if lang != "" {
+		translation, err := translatePost(c, post, lang)
+		if err != nil {
+			renderError(c, err)
+			return
+		}
+		post.ApplyTranslation(translation)
+		lang = translation.Lang
+		v.add("%d:%s:%d", translation.ID, translation.SourceHash, translation.CreatedAt.UnixNano())
+		v.addTime(translation.CreatedAt)
+	}
+
+	// 文章、译文和相关文章都没有变化时返回 304
+	related := relatedPosts(c, post.ID)
+	v.addPosts(related...)
+	if v.notModified(c, pageCacheControl()) {
+		return
+	}
+
 	c.HTML(http.StatusOK, "post.html", gin.H{
-		"post": post,
+		"post":      post,
+		"styles":    llm.StyleNames(),
+		"lang":      lang,
+		"languages": llm.Languages(),
+		"related":   related,
 	})
 }
 
+// 相关文章数量
+const relatedLimit = 5
+
+// 按向量相似度获取相关文章，失败时不影响文章页展示
+func relatedPosts(c *gin.Context, postID uint) []models.Post {
+	matches := embedding.Related(postID, relatedLimit)
+	ids := make([]uint, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.ID)
+	}
+	posts, err := models.GetPostsByIDs(ids)
+	if err != nil {
+		utils.RequestLog(c).WithError(err).WithField("post_id", postID).Error("获取相关文章失败")
+		return nil
+	}
+	return posts
+}
+
 func GeneratePostSummary(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.String(http.StatusBadRequest, "无效的文章ID")
+		renderError(c, errInvalidPostID)
 		return
 	}
 
 	post, err := models.GetPostByID(id)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "获取文章失败")
+		renderError(c, apperr.Wrap(err, "获取文章失败"))
+		return
+	}
+	log := utils.RequestLog(c).WithField("post_id", post.ID)
+
+	// 按文章分类和请求的风格渲染提示词
+	plainText := utils.ExtractText(post.SourceHTML())
+	prompt, err := llm.RenderPrompt(c.Query("style"), llm.PromptData{
+		Title:       post.Title,
+		Category:    post.Category,
+		PublishTime: post.PublishTime,
+		Length:      utf8.RuneCountInString(plainText),
+		Tokens:      llm.EstimateTokens(plainText),
+	})
+	if errors.Is(err, llm.ErrUnknownStyle) {
+		renderError(c, errUnknownStyle)
 		return
 	}
-
-	// 提取纯文本内容
-	plainText := extractText(post.Content)
-
-	// 构建 OpenAI API 请求
-	prompt := fmt.Sprintf("%s\n\n%s", utils.AppConfig.AI.Prompt, plainText)
-
-	// 设置响应头，启用流式响应
-	c.Header("Content-Type", "text/plain")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-
-	// 调用 OpenAI API 并流式传输响应
-	err = streamOpenAIResponse(c.Writer, prompt)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "生成摘要失败")
+		renderError(c, apperr.Wrap(err, "生成摘要失败"))
 		return
 	}
-}
 
-// 提取HTML中的纯文本
-func extractText(htmlContent string) string {
-	doc, err := html.Parse(strings.NewReader(htmlContent))
+	// 缓存键：文章内容、模型和提示词任一变化都会重新生成
+	contentHash := models.HashText(post.Content)
+	model := llm.CacheKey(llm.Default)
+	promptHash := models.HashText(prompt)
+
+	cached, err := models.GetCachedSummary(post.ID, contentHash, model, promptHash)
 	if err != nil {
-		return htmlContent
+		log.WithError(err).Error("读取摘要缓存失败")
 	}
 
-	var buf bytes.Buffer
-	var extract func(*html.Node)
-	extract = func(n *html.Node) {
-		if n.Type == html.TextNode {
-			buf.WriteString(n.Data + " ")
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extract(c)
+	if cached != nil {
+		c.Header("X-Summary-Cache", "HIT")
+	} else {
+		// 需要重新生成时检查本月预算
+		if err := llm.CheckBudget(); err != nil {
+			renderError(c, apperr.Wrap(err, "生成摘要失败"))
+			return
 		}
+		c.Header("X-Summary-Cache", "MISS")
 	}
-	extract(doc)
-	return strings.TrimSpace(buf.String())
-}
 
-// 流式调用 OpenAI API
-func streamOpenAIResponse(w io.Writer, prompt string) error {
-	// OpenAI API 配置
-	var reader *bufio.Reader
-	// 测试环境下使用模拟数据
-	if os.Getenv("OPENAI_ENV") == "DEV" {
-		// 创建一个模拟的响应内容
-		mockResponse := `data: {"choices":[{"delta":{"content":"这是一个"}}]}
-data: {"choices":[{"delta":{"content":"测试摘要"}}]}
-data: {"choices":[{"delta":{"content":"。这篇文章"}}]}
-data: {"choices":[{"delta":{"content":"主要讨论"}}]}
-data: {"choices":[{"delta":{"content":"了某个"}}]}
-data: {"choices":[{"delta":{"content":"技术主题"}}]}
-data: {"choices":[{"delta":{"content":"。"}}]}
-data: [DONE]`
-
-		// 创建一个包含模拟数据的Reader
-		reader = bufio.NewReader(strings.NewReader(mockResponse))
-	} else {
-		// 生产环境下的实际API调用
-		apiKey := utils.AppConfig.AI.ApiKey
-		url := utils.AppConfig.AI.Url
-
-		requestBody := map[string]interface{}{
-			"model": utils.AppConfig.AI.Model,
-			"messages": []map[string]string{
-				{
-					"role":    "user",
-					"content": prompt,
-				},
-			},
-			"stream": true,
-		}
+	// 使用 SSE 流式输出，此后无法再修改状态码，错误通过 error 事件返回
+	stream := newSSEStream(c)
+	defer stream.Close()
 
-		jsonBody, err := json.Marshal(requestBody)
-		if err != nil {
-			return err
-		}
+	// 命中缓存时直接回放
+	if cached != nil {
+		replaySummary(stream, cached.Summary)
+		stream.Send(eventDone, gin.H{"cached": true})
+		return
+	}
 
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return err
-		}
+	// 同一篇文章、同一提示词的并发请求合并为一次上游调用，生成过程分发给所有等待的客户端；
+	// 生成过程的日志带上发起者的请求 ID
+	key := strings.Join([]string{strconv.Itoa(int(post.ID)), contentHash, model, promptHash}, ":")
+	flight, leader := summaryFlights.Join(key, func(ctx context.Context, emit llm.EmitFunc) error {
+		return generateSummary(ctx, log, post, prompt, contentHash, model, promptHash, emit)
+	})
+	if !leader {
+		log.Info("复用进行中的摘要生成")
+	}
 
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+	// 客户端离开只会退订，所有客户端都离开后才会停止生成
+	ctx := c.Request.Context()
+	err = flight.Subscribe(ctx, func(ev llm.Event) error {
+		return stream.Send(ev.Type, ev.Data)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Info("客户端已断开，停止接收摘要")
+			return
+		}
+		stream.Send(eventError, gin.H{"message": "生成摘要失败"})
+		return
+	}
+	stream.Send(eventDone, gin.H{"cached": false})
+}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
+// 进行中的摘要生成
+var summaryFlights llm.FlightGroup
+
+// 生成摘要并写入缓存，进度、增量和用量通过 emit 发布
+func generateSummary(ctx context.Context, log *logrus.Entry, post *models.Post, prompt, contentHash, model, promptHash string, emit llm.EmitFunc) error {
+	// 按段落和标题提取文本，长文章会分块总结后再合并
+	blocks := utils.ExtractBlocks(post.SourceHTML())
+
+	var summary strings.Builder
+	usage, err := llm.Summarize(ctx, llm.WithUsage(llm.Default, "summary", post.ID), prompt, blocks,
+		func(done, total int) {
+			emit(llm.Event{Type: eventProgress, Data: gin.H{"done": done, "total": total}})
+		},
+		func(delta string) error {
+			summary.WriteString(delta)
+			emit(llm.Event{Type: eventDelta, Data: gin.H{"text": delta}})
+			return nil
+		})
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Info("所有客户端已断开，停止生成摘要")
 			return err
 		}
-		defer resp.Body.Close()
-
-		// 读取并转发流式响应
-		reader = bufio.NewReader(resp.Body)
+		log.WithError(err).Error("生成摘要失败")
+		return err
 	}
-	for {
-		line, err := reader.ReadString('\n')
+	emit(llm.Event{Type: eventUsage, Data: usage})
+
+	if summary.Len() > 0 {
+		err = models.SaveSummary(&models.PostSummary{
+			PostID:      post.ID,
+			ContentHash: contentHash,
+			Model:       model,
+			PromptHash:  promptHash,
+			Summary:     summary.String(),
+		})
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return err
+			log.WithError(err).Error("保存摘要缓存失败")
 		}
+	}
+	return nil
+}
 
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				break
-			}
-
-			var response struct {
-				Choices []struct {
-					Delta struct {
-						Content string `json:"content"`
-					} `json:"delta"`
-				} `json:"choices"`
-			}
-
-			if err := json.Unmarshal([]byte(data), &response); err != nil {
-				continue
-			}
-
-			if len(response.Choices) > 0 && response.Choices[0].Delta.Content != "" {
-				w.Write([]byte(response.Choices[0].Delta.Content))
-				if f, ok := w.(http.Flusher); ok {
-					f.Flush()
-				}
-			}
+// 将缓存的摘要分块发送，保持与实时生成一致的流式体验
+func replaySummary(stream *sseStream, summary string) {
+	const chunkSize = 16
+	runes := []rune(summary)
+	for i := 0; i < len(runes); i += chunkSize {
+		end := i + chunkSize
+		if end > len(runes) {
+			end = len(runes)
+		}
+		if err := stream.Send(eventDelta, gin.H{"text": string(runes[i:end])}); err != nil {
+			return
 		}
 	}
-
-	return nil
 }
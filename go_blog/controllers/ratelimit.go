@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"go_blog/apperr"
+	"go_blog/embedding"
+	"go_blog/utils"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 摘要接口的默认限流：每个 IP 每分钟 6 次、突发 3 次，全站每分钟 60 次、突发 20 次
+var defaultSummaryRateLimit = utils.RateLimitConfig{
+	PerIP:       6,
+	PerIPBurst:  3,
+	Global:      60,
+	GlobalBurst: 20,
+}
+
+// 问答接口的默认限流：每个 IP 每分钟 10 次、突发 5 次，全站每分钟 120 次、突发 30 次
+var defaultChatRateLimit = utils.RateLimitConfig{
+	PerIP:       10,
+	PerIPBurst:  5,
+	Global:      120,
+	GlobalBurst: 30,
+}
+
+// 翻译的默认限流：每个 IP 每分钟 2 次、突发 2 次，全站每分钟 10 次、突发 5 次
+var defaultTranslationRateLimit = utils.RateLimitConfig{
+	PerIP:       2,
+	PerIPBurst:  2,
+	Global:      10,
+	GlobalBurst: 5,
+}
+
+// 语义搜索的默认限流：每个 IP 每分钟 10 次、突发 5 次，全站每分钟 60 次、突发 20 次
+var defaultSearchRateLimit = utils.RateLimitConfig{
+	PerIP:       10,
+	PerIPBurst:  5,
+	Global:      60,
+	GlobalBurst: 20,
+}
+
+// 后台登录的默认限流：每个 IP 每分钟 5 次、突发 5 次，全站每分钟 30 次、突发 10 次
+var defaultLoginRateLimit = utils.RateLimitConfig{
+	PerIP:       5,
+	PerIPBurst:  5,
+	Global:      30,
+	GlobalBurst: 10,
+}
+
+// 按接口共享的限流器，同一接口的页面路由和 JSON 路由共用额度
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*utils.RateLimiter)
+)
+
+func getLimiter(name string, cfg, defaults utils.RateLimitConfig) *utils.RateLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[name]
+	if !ok {
+		l = utils.NewRateLimiter(cfg, defaults)
+		limiters[name] = l
+	}
+	return l
+}
+
+// 默认的限流提示
+var errRateLimited = apperr.New(apperr.RateLimited, "rate_limited", "请求过于频繁，请稍后再试")
+
+// 从限流器取一个令牌，超出限制时记录日志、设置 Retry-After 并返回 limited（429），由调用方输出
+func takeToken(c *gin.Context, name string, limiter *utils.RateLimiter, limited *apperr.Error) error {
+	ok, wait := limiter.Allow(c.ClientIP())
+	if ok {
+		return nil
+	}
+	return tooManyRequests(c, name, wait, limited)
+}
+
+// 记录被限流的请求并设置 Retry-After
+func tooManyRequests(c *gin.Context, name string, wait time.Duration, limited *apperr.Error) error {
+	utils.RequestLog(c).WithField("client_ip", c.ClientIP()).WithField("limiter", name).Warn("请求过于频繁")
+	c.Header("Retry-After", strconv.Itoa(utils.RetryAfterSeconds(wait)))
+	return limited
+}
+
+// 限流中间件，超出限制时按 limited 输出 429；skip 不为 nil 且返回 true 的请求不计入
+func rateLimit(name string, cfg, defaults utils.RateLimitConfig, limited *apperr.Error, skip func(*gin.Context) bool) gin.HandlerFunc {
+	limiter := getLimiter(name, cfg, defaults)
+	return func(c *gin.Context) {
+		if skip != nil && skip(c) {
+			c.Next()
+			return
+		}
+		if err := takeToken(c, name, limiter, limited); err != nil {
+			renderError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SummaryRateLimit 摘要接口限流中间件
+func SummaryRateLimit() gin.HandlerFunc {
+	return rateLimit("summary", utils.AppConfig.RateLimit.Summary, defaultSummaryRateLimit, errRateLimited, nil)
+}
+
+// ChatRateLimit 问答接口限流中间件
+func ChatRateLimit() gin.HandlerFunc {
+	return rateLimit("chat", utils.AppConfig.RateLimit.Chat, defaultChatRateLimit, errRateLimited, nil)
+}
+
+// SearchRateLimit 语义搜索限流中间件，只有需要调用向量接口的搜索词才计入，
+// 关键词搜索和已缓存的搜索词不受限制
+func SearchRateLimit() gin.HandlerFunc {
+	return rateLimit("search", utils.AppConfig.RateLimit.Search, defaultSearchRateLimit, errSearchRateLimited,
+		func(c *gin.Context) bool {
+			query := strings.TrimSpace(c.Query("q"))
+			return searchMode(c) != searchModeSemantic || query == "" || embedding.QueryCached(query)
+		})
+}
+
+// LoginRateLimit 后台登录限流中间件，登录页面和登录接口共用额度，限制暴力破解密码。
+// 只有失败的登录（4xx）才消耗额度，登录成功不计入
+func LoginRateLimit() gin.HandlerFunc {
+	limiter := getLimiter("login", utils.AppConfig.RateLimit.Login, defaultLoginRateLimit)
+	return func(c *gin.Context) {
+		if ok, wait := limiter.Peek(c.ClientIP()); !ok {
+			renderError(c, tooManyRequests(c, "login", wait, errLoginRateLimited))
+			return
+		}
+		c.Next()
+		if status := c.Writer.Status(); status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+			limiter.Allow(c.ClientIP())
+		}
+	}
+}
+
+var errCrossSite = apperr.New(apperr.Forbidden, "cross_site", "禁止跨站请求")
+
+// SameOrigin 拒绝浏览器发起的跨站 POST 请求，防止其他网站借访客之手调用接口。
+// 不带 Origin 的请求（如命令行工具）交给限流处理
+func SameOrigin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Sec-Fetch-Site") == "cross-site" {
+			renderError(c, errCrossSite)
+			return
+		}
+		if origin := c.GetHeader("Origin"); origin != "" && !sameOrigin(c, origin) {
+			renderError(c, errCrossSite)
+			return
+		}
+		c.Next()
+	}
+}
+
+// 判断 Origin 是否为本站：配置了 server.baseUrl 时与其比较协议和域名，
+// 否则与请求的 Host 比较，只有来自信任的反向代理时才使用 X-Forwarded-Host
+func sameOrigin(c *gin.Context, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if base := utils.AppConfig.Server.BaseURL; base != "" {
+		b, err := url.Parse(base)
+		if err != nil {
+			return false
+		}
+		return strings.EqualFold(u.Scheme, b.Scheme) && strings.EqualFold(u.Host, b.Host)
+	}
+
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" && fromTrustedProxy(c) {
+		// 经过多层代理时取最靠近客户端的一个
+		host, _, _ = strings.Cut(forwarded, ",")
+		host = strings.TrimSpace(host)
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+// 判断请求是否直接来自配置的反向代理（server.trustedProxies，IP 或 CIDR）
+func fromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range utils.AppConfig.Server.TrustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(proxy); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"go_blog/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	utils.Log = logrus.New()
+	utils.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func TestLoginRateLimitCountsFailuresOnly(t *testing.T) {
+	limitersMu.Lock()
+	delete(limiters, "login")
+	limitersMu.Unlock()
+	t.Cleanup(func() {
+		limitersMu.Lock()
+		delete(limiters, "login")
+		limitersMu.Unlock()
+	})
+
+	r := gin.New()
+	r.POST("/api/v1/admin/login", LoginRateLimit(), func(c *gin.Context) {
+		if c.PostForm("password") != "secret" {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	login := func(password string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/login", strings.NewReader("password="+password))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 成功的登录不消耗额度
+	for i := 0; i < defaultLoginRateLimit.PerIPBurst*2; i++ {
+		if code := login("secret"); code != http.StatusOK {
+			t.Fatalf("第 %d 次成功登录返回 %d", i+1, code)
+		}
+	}
+	for i := 0; i < defaultLoginRateLimit.PerIPBurst; i++ {
+		if code := login("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("第 %d 次失败登录返回 %d，期望 401", i+1, code)
+		}
+	}
+	if code := login("wrong"); code != http.StatusTooManyRequests {
+		t.Errorf("失败次数超过额度后返回 %d，期望 429", code)
+	}
+	if code := login("secret"); code != http.StatusTooManyRequests {
+		t.Errorf("额度用完后正确的密码返回 %d，期望 429", code)
+	}
+}
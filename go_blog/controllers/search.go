@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go_blog/apperr"
+	"go_blog/embedding"
+	"go_blog/llm"
+	"go_blog/models"
+	"go_blog/search"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchResult 搜索结果，包含文章和高亮片段
+type SearchResult struct {
+	Post    models.Post
+	Score   float64
+	Title   template.HTML
+	Snippet template.HTML
+}
+
+// 搜索模式
+const (
+	searchModeKeyword  = "keyword"
+	searchModeSemantic = "semantic"
+)
+
+// AI 预算用完时语义搜索不可用
+var errSemanticUnavailable = apperr.New(apperr.Unavailable, "budget_exceeded", "语义搜索暂不可用，请使用关键词搜索")
+
+// 语义搜索过于频繁
+var errSearchRateLimited = apperr.New(apperr.RateLimited, "rate_limited", "语义搜索过于频繁，请稍后再试或使用关键词搜索")
+
+// 片段截取长度（字符数）
+const snippetWidth = 160
+
+// 执行搜索并加载命中的文章，semantic 模式按向量相似度检索
+func doSearch(ctx context.Context, query, mode string, page, pageSize int) ([]SearchResult, int64, error) {
+	var hits []search.Hit
+	var total int64
+	if mode == searchModeSemantic {
+		matches, n, err := embedding.Search(ctx, query, (page-1)*pageSize, pageSize)
+		if errors.Is(err, llm.ErrBudgetExceeded) {
+			return nil, 0, errSemanticUnavailable
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, m := range matches {
+			hits = append(hits, search.Hit{ID: m.ID, Score: m.Score})
+		}
+		total = n
+	} else {
+		var err error
+		hits, total, err = search.Default.Search(query, (page-1)*pageSize, pageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	posts, err := models.GetPostsByIDs(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint]models.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		post, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		// 语义检索没有命中的关键词，直接展示标题和摘要
+		if hit.Title == "" {
+			hit.Title = search.Highlight(post.Title, nil, 0)
+			hit.Snippet = search.Highlight(post.Summary, nil, snippetWidth)
+		}
+		results = append(results, SearchResult{
+			Post:    post,
+			Score:   hit.Score,
+			Title:   hit.Title,
+			Snippet: hit.Snippet,
+		})
+	}
+	return results, total, nil
+}
+
+// 读取搜索模式，默认为关键词搜索
+func searchMode(c *gin.Context) string {
+	if c.Query("mode") == searchModeSemantic {
+		return searchModeSemantic
+	}
+	return searchModeKeyword
+}
+
+// Search 搜索页面
+func Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	mode := searchMode(c)
+	page, _ := parsePage(c)
+	pageSize, _ := parsePageSize(c)
+
+	var results []SearchResult
+	var total int64
+	if query != "" {
+		var err error
+		results, total, err = doSearch(c.Request.Context(), query, mode, page, pageSize)
+		if err != nil {
+			renderError(c, apperr.Wrap(err, "搜索失败"))
+			return
+		}
+	}
+
+	c.HTML(http.StatusOK, "search.html", gin.H{
+		"query":      query,
+		"mode":       mode,
+		"results":    results,
+		"page":       page,
+		"totalPages": (int(total) + pageSize - 1) / pageSize,
+		"totalPosts": total,
+		"pageQuery":  searchPageQuery(c, query, mode),
+	})
+}
+
+// APISearch 搜索接口
+func APISearch(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		apiError(c, http.StatusBadRequest, "invalid_query", "搜索关键词不能为空")
+		return
+	}
+	mode := searchMode(c)
+	page, ok := parsePage(c)
+	if !ok {
+		apiError(c, http.StatusBadRequest, "invalid_page", "页码必须是大于 0 的整数")
+		return
+	}
+	pageSize, ok := parsePageSize(c)
+	if !ok {
+		apiError(c, http.StatusBadRequest, "invalid_page_size", "无效的每页数量")
+		return
+	}
+
+	results, total, err := doSearch(c.Request.Context(), query, mode, page, pageSize)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "搜索失败"))
+		return
+	}
+
+	items := make([]gin.H, 0, len(results))
+	for i := range results {
+		items = append(items, gin.H{
+			"post":    newAPIPost(&results[i].Post, false),
+			"score":   results[i].Score,
+			"title":   results[i].Title,
+			"snippet": results[i].Snippet,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":      query,
+		"mode":       mode,
+		"results":    items,
+		"page":       page,
+		"pageSize":   pageSize,
+		"total":      total,
+		"totalPages": (int(total) + pageSize - 1) / pageSize,
+	})
+}
+
+// 搜索结果分页链接需要保留的查询参数
+func searchPageQuery(c *gin.Context, query, mode string) template.URL {
+	values := url.Values{"q": {query}, "mode": {mode}}
+	if v := c.Query("page_size"); v != "" {
+		values.Set("page_size", v)
+	}
+	return template.URL(values.Encode() + "&")
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SSE 事件类型
+const (
+	eventDelta    = "delta"
+	eventProgress = "progress"
+	eventUsage    = "usage"
+	eventError    = "error"
+	eventDone     = "done"
+)
+
+// 心跳间隔，避免代理因长时间无数据断开连接
+const sseHeartbeatInterval = 15 * time.Second
+
+// sseStream Server-Sent Events 输出，事件和心跳可能并发写入，需加锁
+type sseStream struct {
+	mu   sync.Mutex
+	w    gin.ResponseWriter
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// 写入 SSE 响应头并启动心跳，结束时必须调用 Close
+func newSSEStream(c *gin.Context) *sseStream {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	s := &sseStream{w: c.Writer, stop: make(chan struct{})}
+	s.wg.Add(1)
+	go s.heartbeat()
+	return s
+}
+
+func (s *sseStream) heartbeat() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(sseHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.write(": ping\n\n")
+		}
+	}
+}
+
+func (s *sseStream) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.WriteString(text); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return nil
+}
+
+// Send 发送一个事件，data 序列化为 JSON
+func (s *sseStream) Send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return s.write(fmt.Sprintf("event: %s\ndata: %s\n\n", event, payload))
+}
+
+// Close 停止心跳，处理函数返回后不能再写入响应
+func (s *sseStream) Close() {
+	close(s.stop)
+	s.wg.Wait()
+}
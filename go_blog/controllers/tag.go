@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"go_blog/apperr"
+	"go_blog/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 首页标签云展示的标签数
+const tagCloudSize = 50
+
+// TagCloudItem 标签云中的标签，Weight 为 1–5 的字号等级
+type TagCloudItem struct {
+	models.TagCount
+	Weight int
+}
+
+// 按文章数计算标签云的字号等级
+func tagCloud(counts []models.TagCount) []TagCloudItem {
+	if len(counts) == 0 {
+		return nil
+	}
+	min, max := counts[0].Count, counts[0].Count
+	for _, t := range counts {
+		if t.Count < min {
+			min = t.Count
+		}
+		if t.Count > max {
+			max = t.Count
+		}
+	}
+
+	items := make([]TagCloudItem, 0, len(counts))
+	for _, t := range counts {
+		weight := 3
+		if max > min {
+			weight = 1 + int(4*(t.Count-min)/(max-min))
+		}
+		items = append(items, TagCloudItem{TagCount: t, Weight: weight})
+	}
+	return items
+}
+
+// 从查询参数中读取标签筛选条件，支持 tag=a&tag=b 和 tag=a,b 两种写法
+func queryTags(c *gin.Context) []string {
+	return models.ParseTags(strings.Join(c.QueryArray("tag"), ","))
+}
+
+// APITagList 获取标签及其文章数
+func APITagList(c *gin.Context) {
+	tags, err := models.GetTagCounts(0)
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取标签失败"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tags": tags,
+	})
+}
+
+// AdminTagList 后台标签管理页面
+func AdminTagList(c *gin.Context) {
+	renderAdminTags(c, http.StatusOK, "")
+}
+
+// AdminRenameTag 重命名标签，新名称已存在时合并
+func AdminRenameTag(c *gin.Context) {
+	from, to := c.PostForm("from"), c.PostForm("to")
+	if err := models.RenameTag(from, to); err != nil {
+		renderAdminTagError(c, err)
+		return
+	}
+	audit(c, "tag", 0, "重命名标签: "+from+" -> "+to)
+	c.Redirect(http.StatusFound, "/admin/tags")
+}
+
+// AdminMergeTags 将多个标签合并到目标标签
+func AdminMergeTags(c *gin.Context) {
+	from := models.ParseTags(c.PostForm("from"))
+	to := c.PostForm("to")
+	if err := models.MergeTags(from, to); err != nil {
+		renderAdminTagError(c, err)
+		return
+	}
+	audit(c, "tag", 0, "合并标签: "+strings.Join(from, ",")+" -> "+to)
+	c.Redirect(http.StatusFound, "/admin/tags")
+}
+
+// AdminDeleteTag 删除标签
+func AdminDeleteTag(c *gin.Context) {
+	name := c.PostForm("name")
+	if err := models.DeleteTag(name); err != nil {
+		renderAdminTagError(c, err)
+		return
+	}
+	audit(c, "tag", 0, "删除标签: "+name)
+	c.Redirect(http.StatusFound, "/admin/tags")
+}
+
+func renderAdminTags(c *gin.Context, status int, message string) {
+	tags, err := models.AdminGetTags()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取标签失败"))
+		return
+	}
+	c.HTML(status, "admin_tags.html", gin.H{
+		"tags":      tags,
+		"error":     message,
+		"csrfToken": c.GetString("csrfToken"),
+	})
+}
+
+// TagRequest 接口重命名或合并标签的请求
+type TagRequest struct {
+	From []string `json:"from"`
+	To   string   `json:"to"`
+}
+
+// APIAdminTagList 后台接口获取所有标签，包括只用于未发布文章的标签
+func APIAdminTagList(c *gin.Context) {
+	tags, err := models.AdminGetTags()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取标签失败"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tags": tags,
+	})
+}
+
+// APIAdminRenameTag 接口重命名标签，新名称已存在时合并
+func APIAdminRenameTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apiError(c, http.StatusBadRequest, "invalid_request", "请求格式错误")
+		return
+	}
+	from := c.Param("tag")
+	if err := models.RenameTag(from, req.To); err != nil {
+		renderError(c, apperr.Wrap(err, "修改标签失败"))
+		return
+	}
+	audit(c, "tag", 0, "重命名标签: "+from+" -> "+req.To)
+	c.Status(http.StatusNoContent)
+}
+
+// APIAdminMergeTags 接口将多个标签合并到目标标签
+func APIAdminMergeTags(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.From) == 0 {
+		apiError(c, http.StatusBadRequest, "invalid_request", "请求格式错误")
+		return
+	}
+	if err := models.MergeTags(req.From, req.To); err != nil {
+		renderError(c, apperr.Wrap(err, "修改标签失败"))
+		return
+	}
+	audit(c, "tag", 0, "合并标签: "+strings.Join(req.From, ",")+" -> "+req.To)
+	c.Status(http.StatusNoContent)
+}
+
+// APIAdminDeleteTag 接口删除标签
+func APIAdminDeleteTag(c *gin.Context) {
+	name := c.Param("tag")
+	if err := models.DeleteTag(name); err != nil {
+		renderError(c, apperr.Wrap(err, "修改标签失败"))
+		return
+	}
+	audit(c, "tag", 0, "删除标签: "+name)
+	c.Status(http.StatusNoContent)
+}
+
+// AdminImportTags 导入 go_cli 文章表中的标签，只处理还没有标签的文章
+func AdminImportTags(c *gin.Context) {
+	n, err := models.ImportArticleTags()
+	if err != nil {
+		renderAdminTagError(c, err)
+		return
+	}
+	audit(c, "tag", 0, "导入 go_cli 文章标签: "+strconv.Itoa(n)+" 篇")
+	c.Redirect(http.StatusFound, "/admin/tags")
+}
+
+// APIAdminImportTags 接口导入 go_cli 文章表中的标签，返回导入了标签的文章数
+func APIAdminImportTags(c *gin.Context) {
+	n, err := models.ImportArticleTags()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "导入标签失败"))
+		return
+	}
+	audit(c, "tag", 0, "导入 go_cli 文章标签: "+strconv.Itoa(n)+" 篇")
+	c.JSON(http.StatusOK, gin.H{
+		"imported": n,
+	})
+}
+
+// 标签修改失败时在管理页面展示原因，内部错误只展示提示
+func renderAdminTagError(c *gin.Context, err error) {
+	e := apperr.Wrap(err, "修改标签失败")
+	logError(c, e)
+	renderAdminTags(c, e.Status(), e.Message)
+}
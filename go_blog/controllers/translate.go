@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go_blog/apperr"
+	"go_blog/llm"
+	"go_blog/models"
+	"go_blog/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 进行中的翻译，同一篇文章同一语言的并发请求只翻译一次
+var translationFlights llm.FlightGroup
+
+// 翻译请求过于频繁
+var errTranslationRateLimited = apperr.New(apperr.RateLimited, "rate_limited", "翻译请求过于频繁，请稍后再试")
+
+// 获取文章的译文，没有可用的译文时调用模型翻译并保存；失败时返回业务错误，由 renderError 输出
+func translatePost(c *gin.Context, post *models.Post, code string) (*models.PostTranslation, error) {
+	lang, err := llm.FindLanguage(code)
+	if err != nil {
+		return nil, apperr.NewInvalid("invalid_lang", err.Error())
+	}
+
+	log := utils.RequestLog(c).WithField("post_id", post.ID)
+
+	sourceHash := post.SourceHash()
+	cached, err := models.GetTranslation(post.ID, lang.Code, sourceHash)
+	if err != nil {
+		log.WithError(err).Error("读取译文失败")
+	}
+	if cached != nil {
+		c.Header("X-Translation-Cache", "HIT")
+		return cached, nil
+	}
+
+	// 只有需要调用模型时才限流和检查预算
+	limiter := getLimiter("translation", utils.AppConfig.RateLimit.Translation, defaultTranslationRateLimit)
+	if err := takeToken(c, "translation", limiter, errTranslationRateLimited); err != nil {
+		return nil, err
+	}
+	if err := llm.CheckBudget(); err != nil {
+		return nil, apperr.Wrap(err, "翻译失败")
+	}
+
+	key := fmt.Sprintf("%d:%s:%s", post.ID, lang.Code, sourceHash)
+	// 翻译在发起请求的 goroutine 之外进行，日志带上发起者的请求 ID
+	flight, _ := translationFlights.Join(key, func(ctx context.Context, emit llm.EmitFunc) error {
+		result, _, err := llm.Translate(ctx, llm.WithUsage(llm.Default, "translation", post.ID),
+			lang, post.Title, post.Summary, post.SourceHTML())
+		if err != nil {
+			if ctx.Err() == nil {
+				log.WithError(err).Error("翻译文章失败")
+			}
+			return err
+		}
+
+		t := &models.PostTranslation{
+			PostID:     post.ID,
+			Lang:       lang.Code,
+			SourceHash: sourceHash,
+			Model:      llm.CacheKey(llm.Default),
+			Title:      result.Title,
+			Summary:    result.Summary,
+			Content:    result.Content,
+		}
+		// 保存失败时返回错误，否则之后的每次请求都会重新调用模型
+		if err := models.SaveTranslation(t); err != nil {
+			log.WithError(err).Error("保存译文失败")
+			return apperr.Wrap(err, "保存译文失败，请稍后重试")
+		}
+		emit(llm.Event{Type: "translation", Data: t})
+		return nil
+	})
+
+	var translation *models.PostTranslation
+	err = flight.Subscribe(c.Request.Context(), func(ev llm.Event) error {
+		translation, _ = ev.Data.(*models.PostTranslation)
+		return nil
+	})
+	// 预算用完、保存失败等已分类的错误按原类型返回，其余视为服务商失败
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return nil, apperr.From(err)
+	}
+	if err != nil || translation == nil {
+		return nil, apperr.NewUpstream("翻译失败，请稍后重试", err)
+	}
+	c.Header("X-Translation-Cache", "MISS")
+	return translation, nil
+}
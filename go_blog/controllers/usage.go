@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"go_blog/apperr"
+	"go_blog/llm"
+	"go_blog/models"
+	"go_blog/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 用量页面展示的天数
+const usageDays = 30
+
+// UsageSummary 本月用量概况
+type UsageSummary struct {
+	MonthCost        float64 `json:"monthCost"`
+	MonthlyBudget    float64 `json:"monthlyBudget"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+}
+
+// 读取最近的每日用量并汇总本月数据
+func loadUsage() ([]models.AIUsageDaily, UsageSummary, error) {
+	since := time.Now().AddDate(0, 0, -usageDays)
+	monthStart := llm.MonthStart()
+	if monthStart.Before(since) {
+		since = monthStart
+	}
+
+	rows, err := models.GetDailyUsage(since)
+	if err != nil {
+		return nil, UsageSummary{}, err
+	}
+
+	summary := UsageSummary{MonthlyBudget: utils.AppConfig.AI.MonthlyBudget}
+	for _, row := range rows {
+		if row.Day.Before(monthStart) {
+			continue
+		}
+		summary.MonthCost += row.Cost
+		summary.Calls += row.Calls
+		summary.PromptTokens += row.PromptTokens
+		summary.CompletionTokens += row.CompletionTokens
+	}
+	return rows, summary, nil
+}
+
+// AdminUsage 后台 AI 用量页面
+func AdminUsage(c *gin.Context) {
+	rows, summary, err := loadUsage()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取用量失败"))
+		return
+	}
+
+	c.HTML(http.StatusOK, "admin_usage.html", gin.H{
+		"rows":    rows,
+		"summary": summary,
+	})
+}
+
+// APIAdminUsage 后台接口获取 AI 用量
+func APIAdminUsage(c *gin.Context) {
+	rows, summary, err := loadUsage()
+	if err != nil {
+		renderError(c, apperr.Wrap(err, "获取用量失败"))
+		return
+	}
+
+	daily := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		daily = append(daily, gin.H{
+			"day":              row.Day.Format("2006-01-02"),
+			"feature":          row.Feature,
+			"model":            row.Model,
+			"calls":            row.Calls,
+			"promptTokens":     row.PromptTokens,
+			"completionTokens": row.CompletionTokens,
+			"cost":             row.Cost,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary": summary,
+		"daily":   daily,
+	})
+}
+
+var errMetricsUnauthorized = apperr.New(apperr.Unauthorized, "unauthorized", "缺少或错误的 Bearer Token")
+
+// Metrics 以 Prometheus 文本格式输出指标，配置了 server.metricsToken 时需要携带 Bearer Token
+func Metrics(c *gin.Context) {
+	if token := utils.AppConfig.Server.MetricsToken; token != "" {
+		got, ok := bearerToken(c)
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			renderError(c, errMetricsUnauthorized)
+			return
+		}
+	}
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	llm.WriteMetrics(c.Writer)
+}
+
+// 从 Authorization 头中取出 Bearer Token，认证方案不区分大小写
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,334 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"go_blog/llm"
+	"go_blog/models"
+	"go_blog/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 参与计算向量的正文最大字符数，避免超出向量模型的输入上限
+const maxBodyRunes = 2000
+
+// 默认每次请求计算的文章数
+const defaultBatchSize = 16
+
+// 缓存的搜索词向量数量上限
+const queryCacheSize = 256
+
+// Match 语义检索结果
+type Match struct {
+	ID    uint
+	Score float64
+}
+
+// Index 进程内向量索引，向量均已归一化，余弦相似度即点积
+type Index struct {
+	mu      sync.RWMutex
+	vectors map[uint][]float32
+}
+
+// Default 当前向量模型下所有已发布文章的向量
+var Default = &Index{vectors: make(map[uint][]float32)}
+
+func (idx *Index) set(id uint, v []float32) {
+	llm.Normalize(v)
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.vectors[id] = v
+}
+
+func (idx *Index) remove(id uint) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.vectors, id)
+}
+
+func (idx *Index) get(id uint) ([]float32, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	v, ok := idx.vectors[id]
+	return v, ok
+}
+
+// Len 已索引的文章数
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.vectors)
+}
+
+// Search 按与 query 的余弦相似度排序，exclude 不为 0 时排除该文章，
+// 只返回相似度超过 minScore 的结果
+func (idx *Index) Search(query []float32, exclude uint, minScore float64, offset, limit int) ([]Match, int64) {
+	idx.mu.RLock()
+	matches := make([]Match, 0, len(idx.vectors))
+	for id, v := range idx.vectors {
+		if id == exclude || len(v) != len(query) {
+			continue
+		}
+		if score := dot(query, v); score > minScore {
+			matches = append(matches, Match{ID: id, Score: score})
+		}
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	total := int64(len(matches))
+	if offset >= len(matches) {
+		return nil, total
+	}
+	end := offset + limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+	return matches[offset:end], total
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// Text 生成用于计算向量的文本：标题、摘要和正文开头
+func Text(post *models.Post) string {
+	body := []rune(utils.ExtractText(post.SourceHTML()))
+	if len(body) > maxBodyRunes {
+		body = body[:maxBodyRunes]
+	}
+	return strings.TrimSpace(post.Title + "\n" + post.Summary + "\n" + string(body))
+}
+
+// Init 加载当前模型的向量，并在后台补全缺失或过期的向量
+func Init() error {
+	if err := load(); err != nil {
+		return err
+	}
+
+	go func() {
+		if err := Backfill(context.Background()); err != nil {
+			utils.Log.WithError(err).Error("补全文章向量失败")
+		}
+		interval := utils.AppConfig.AI.Embedding.BackfillInterval
+		if interval <= 0 {
+			return
+		}
+		// 爬虫等外部程序会直接写数据库，定时补全以保持同步
+		ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := load(); err != nil {
+				utils.Log.WithError(err).Error("加载文章向量失败")
+			}
+			if err := Backfill(context.Background()); err != nil {
+				utils.Log.WithError(err).Error("补全文章向量失败")
+			}
+		}
+	}()
+	return nil
+}
+
+// 从数据库加载当前模型下所有已发布文章的向量，替换内存中的索引
+func load() error {
+	embeddings, err := models.GetEmbeddings(llm.DefaultEmbedder.Model())
+	if err != nil {
+		return err
+	}
+	vectors := make(map[uint][]float32, len(embeddings))
+	for _, e := range embeddings {
+		v := models.DecodeVector(e.Vector)
+		llm.Normalize(v)
+		vectors[e.PostID] = v
+	}
+	Default.mu.Lock()
+	Default.vectors = vectors
+	Default.mu.Unlock()
+	return nil
+}
+
+// 待计算向量的文章
+type pending struct {
+	id   uint
+	text string
+	hash string
+}
+
+// Backfill 为没有向量、文本已变化或模型已更换的已发布文章计算向量
+func Backfill(ctx context.Context) error {
+	model := llm.DefaultEmbedder.Model()
+	versions, err := models.GetEmbeddingVersions()
+	if err != nil {
+		return err
+	}
+
+	var todo []pending
+	err = models.EachPublishedPost(func(post *models.Post) error {
+		if p, ok := needsEmbedding(post, versions, model); ok {
+			todo = append(todo, p)
+		}
+		return nil
+	})
+	if err != nil || len(todo) == 0 {
+		return err
+	}
+
+	batchSize := utils.AppConfig.AI.Embedding.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	utils.Log.WithField("posts", len(todo)).Info("开始补全文章向量")
+	for start := 0; start < len(todo); start += batchSize {
+		end := start + batchSize
+		if end > len(todo) {
+			end = len(todo)
+		}
+		if err := embedBatch(ctx, todo[start:end]); err != nil {
+			return err
+		}
+	}
+	utils.Log.WithField("posts", len(todo)).Info("文章向量补全完成")
+	return nil
+}
+
+// 判断文章是否需要计算向量：没有向量、文本已变化或模型已更换
+func needsEmbedding(post *models.Post, versions map[uint]models.PostEmbedding, model string) (pending, bool) {
+	text := Text(post)
+	hash := models.HashText(text)
+	if v, ok := versions[post.ID]; ok && v.Model == model && v.SourceHash == hash {
+		return pending{}, false
+	}
+	return pending{id: post.ID, text: text, hash: hash}, true
+}
+
+// 计算一批文章的向量并保存
+func embedBatch(ctx context.Context, batch []pending) error {
+	if err := llm.CheckBudget(); err != nil {
+		return err
+	}
+
+	texts := make([]string, len(batch))
+	for i, p := range batch {
+		texts[i] = p.text
+	}
+	vectors, _, err := llm.DefaultEmbedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+
+	model := llm.DefaultEmbedder.Model()
+	for i, p := range batch {
+		err := models.SaveEmbedding(&models.PostEmbedding{
+			PostID:     p.id,
+			Model:      model,
+			SourceHash: p.hash,
+			Dimensions: len(vectors[i]),
+			Vector:     models.EncodeVector(vectors[i]),
+		})
+		if err != nil {
+			return err
+		}
+		Default.set(p.id, vectors[i])
+	}
+	return nil
+}
+
+// RefreshPost 文章变更后在后台更新向量，未发布或已删除的文章会从索引中移除
+func RefreshPost(id uint) {
+	if llm.DefaultEmbedder == nil {
+		return
+	}
+	post, err := models.GetPostByID(int(id))
+	if err != nil {
+		Default.remove(id)
+		return
+	}
+
+	text := Text(post)
+	go func() {
+		err := embedBatch(context.Background(), []pending{{id: id, text: text, hash: models.HashText(text)}})
+		if err != nil && !errors.Is(err, llm.ErrBudgetExceeded) {
+			utils.Log.WithError(err).WithField("post_id", id).Error("更新文章向量失败")
+		}
+	}()
+}
+
+// 搜索词向量缓存，避免重复搜索时反复调用向量服务
+var (
+	queryMu    sync.Mutex
+	queryCache = make(map[string][]float32)
+)
+
+func embedQuery(ctx context.Context, query string) ([]float32, error) {
+	key := queryKey(query)
+	queryMu.Lock()
+	v, ok := queryCache[key]
+	queryMu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	if err := llm.CheckBudget(); err != nil {
+		return nil, err
+	}
+	vectors, _, err := llm.DefaultEmbedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	v = vectors[0]
+	llm.Normalize(v)
+
+	queryMu.Lock()
+	if len(queryCache) >= queryCacheSize {
+		queryCache = make(map[string][]float32)
+	}
+	queryCache[key] = v
+	queryMu.Unlock()
+	return v, nil
+}
+
+// 搜索词向量缓存的键，更换向量模型后重新计算
+func queryKey(query string) string {
+	return llm.DefaultEmbedder.Model() + "\x00" + query
+}
+
+// QueryCached 搜索词的向量是否已缓存，已缓存时语义检索不会调用向量接口
+func QueryCached(query string) bool {
+	queryMu.Lock()
+	defer queryMu.Unlock()
+	_, ok := queryCache[queryKey(query)]
+	return ok
+}
+
+// Search 语义检索，按与搜索词的余弦相似度排序
+func Search(ctx context.Context, query string, offset, limit int) ([]Match, int64, error) {
+	v, err := embedQuery(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	matches, total := Default.Search(v, 0, utils.AppConfig.AI.Embedding.MinScore, offset, limit)
+	return matches, total, nil
+}
+
+// Related 与指定文章最相似的文章，文章还没有向量时返回空
+func Related(postID uint, limit int) []Match {
+	v, ok := Default.get(postID)
+	if !ok {
+		return nil
+	}
+	matches, _ := Default.Search(v, postID, utils.AppConfig.AI.Embedding.MinScore, 0, limit)
+	return matches
+}
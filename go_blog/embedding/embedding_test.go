@@ -0,0 +1,193 @@
+package embedding
+
+import (
+	"context"
+	"go_blog/llm"
+	"go_blog/models"
+	"go_blog/utils"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// 测试使用本地向量，不调用外部服务、不检查预算
+	utils.Log = logrus.New()
+	utils.Log.SetOutput(io.Discard)
+	utils.AppConfig.AI.MonthlyBudget = 0
+	llm.DefaultEmbedder = llm.NewLocalEmbedder(64)
+	os.Exit(m.Run())
+}
+
+// 用给定的向量创建索引
+func newIndex(vectors map[uint][]float32) *Index {
+	idx := &Index{vectors: make(map[uint][]float32)}
+	for id, v := range vectors {
+		idx.set(id, v)
+	}
+	return idx
+}
+
+func ids(matches []Match) []uint {
+	out := make([]uint, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.ID)
+	}
+	return out
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := newIndex(map[uint][]float32{
+		1: {1, 0, 0},
+		2: {0.9, 0.1, 0},
+		3: {0.5, 0.5, 0},
+		4: {0, 0, 1},
+		5: {1, 0}, // 维度不同，忽略
+	})
+	query := []float32{1, 0, 0}
+
+	tests := []struct {
+		name      string
+		exclude   uint
+		minScore  float64
+		offset    int
+		limit     int
+		want      []uint
+		wantTotal int64
+	}{
+		{"按相似度排序", 0, 0, 0, 10, []uint{1, 2, 3}, 3},
+		{"低于最低相似度的不返回", 0, 0.8, 0, 10, []uint{1, 2}, 2},
+		{"排除指定文章", 1, 0, 0, 10, []uint{2, 3}, 2},
+		{"分页", 0, 0, 1, 1, []uint{2}, 3},
+		{"超出范围", 0, 0, 5, 10, []uint{}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, total := idx.Search(query, tt.exclude, tt.minScore, tt.offset, tt.limit)
+			if got := ids(matches); !reflect.DeepEqual(got, tt.want) || total != tt.wantTotal {
+				t.Errorf("Search = %v (%d)，期望 %v (%d)", got, total, tt.want, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestIndexSearchCosine(t *testing.T) {
+	// 长度不同但方向相同的向量归一化后相似度为 1
+	idx := newIndex(map[uint][]float32{1: {3, 4}, 2: {4, 3}})
+	query := []float32{0.6, 0.8}
+	matches, _ := idx.Search(query, 0, 0, 0, 10)
+	if len(matches) != 2 || matches[0].ID != 1 {
+		t.Fatalf("Search = %v", matches)
+	}
+	if d := matches[0].Score - 1; d > 1e-6 || d < -1e-6 {
+		t.Errorf("相同方向的相似度 = %v，期望 1", matches[0].Score)
+	}
+	if d := matches[1].Score - 0.96; d > 1e-6 || d < -1e-6 {
+		t.Errorf("相似度 = %v，期望 0.96", matches[1].Score)
+	}
+}
+
+func TestRelatedExcludesSelf(t *testing.T) {
+	old := Default
+	t.Cleanup(func() { Default = old })
+	Default = newIndex(map[uint][]float32{
+		1: {1, 0, 0},
+		2: {0.9, 0.1, 0},
+		3: {0, 1, 0},
+	})
+
+	got := ids(Related(1, 5))
+	if !reflect.DeepEqual(got, []uint{2}) {
+		t.Errorf("Related(1) = %v，期望 [2]", got)
+	}
+	if got := Related(9, 5); got != nil {
+		t.Errorf("没有向量的文章 Related = %v，期望为空", got)
+	}
+}
+
+func TestNeedsEmbedding(t *testing.T) {
+	post := &models.Post{Title: "Go 并发", Summary: "goroutine 和 channel", Content: "<p>正文</p>"}
+	post.ID = 1
+	model := llm.DefaultEmbedder.Model()
+	hash := models.HashText(Text(post))
+
+	tests := []struct {
+		name     string
+		versions map[uint]models.PostEmbedding
+		want     bool
+	}{
+		{"没有向量", map[uint]models.PostEmbedding{}, true},
+		{"文本未变化", map[uint]models.PostEmbedding{1: {PostID: 1, Model: model, SourceHash: hash}}, false},
+		{"文本已变化", map[uint]models.PostEmbedding{1: {PostID: 1, Model: model, SourceHash: "old"}}, true},
+		{"模型已更换", map[uint]models.PostEmbedding{1: {PostID: 1, Model: "other", SourceHash: hash}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := needsEmbedding(post, tt.versions, model)
+			if ok != tt.want {
+				t.Fatalf("needsEmbedding = %v，期望 %v", ok, tt.want)
+			}
+			if ok && (p.id != 1 || p.hash != hash) {
+				t.Errorf("pending = %+v", p)
+			}
+		})
+	}
+}
+
+func TestLocalEmbedderDeterministic(t *testing.T) {
+	texts := []string{"Go 语言的并发模型", "JavaScript 数组方法", "Go 语言的并发模型"}
+	a, _, err := llm.NewLocalEmbedder(64).Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _, err := llm.NewLocalEmbedder(64).Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("相同文本在不同实例中的向量不同")
+	}
+	if !reflect.DeepEqual(a[0], a[2]) {
+		t.Error("相同文本的向量不同")
+	}
+	if len(a[0]) != 64 {
+		t.Errorf("维度 = %d，期望 64", len(a[0]))
+	}
+}
+
+func TestSearchWithLocalEmbedder(t *testing.T) {
+	old := Default
+	t.Cleanup(func() { Default = old })
+
+	texts := map[uint]string{
+		1: "Go 语言 goroutine channel 并发",
+		2: "JavaScript 数组 map filter reduce",
+		3: "CSS flex 布局 居中",
+	}
+	Default = &Index{vectors: make(map[uint][]float32)}
+	for id, text := range texts {
+		vectors, _, err := llm.DefaultEmbedder.Embed(context.Background(), []string{text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		Default.set(id, vectors[0])
+	}
+
+	query := "goroutine channel"
+	if QueryCached(query) {
+		t.Fatal("搜索前不应有缓存")
+	}
+	matches, total, err := Search(context.Background(), query, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total == 0 || matches[0].ID != 1 {
+		t.Errorf("Search = %v，期望第一条为 1", matches)
+	}
+	if !QueryCached(query) {
+		t.Error("搜索后应缓存搜索词向量")
+	}
+}
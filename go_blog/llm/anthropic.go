@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go_blog/utils"
+	"io"
+)
+
+// Anthropic Anthropic 风格的 messages 接口
+type Anthropic struct {
+	cfg    utils.ProviderConfig
+	client *httpClient
+}
+
+// NewAnthropic 创建 Anthropic 服务商
+func NewAnthropic(cfg utils.ProviderConfig) *Anthropic {
+	if cfg.Url == "" {
+		cfg.Url = "https://api.anthropic.com/v1/messages"
+	}
+	if cfg.Version == "" {
+		cfg.Version = "2023-06-01"
+	}
+	// messages 接口要求必须指定 max_tokens
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = 1024
+	}
+	return &Anthropic{cfg: cfg, client: newHTTPClient("anthropic")}
+}
+
+func (p *Anthropic) Name() string  { return "anthropic" }
+func (p *Anthropic) Model() string { return p.cfg.Model }
+
+// Stream 调用 messages 接口并解析 SSE 事件
+func (p *Anthropic) Stream(ctx context.Context, req Request, onDelta DeltaFunc) (Usage, error) {
+	var usage Usage
+
+	body := map[string]interface{}{
+		"model":      p.cfg.Model,
+		"max_tokens": p.cfg.MaxTokens,
+		"messages":   req.Messages,
+		"stream":     true,
+	}
+	if req.System != "" {
+		body["system"] = req.System
+	}
+
+	resp, err := p.client.postJSON(ctx, p.cfg.Url, body, map[string]string{
+		"x-api-key":         p.cfg.ApiKey,
+		"anthropic-version": p.cfg.Version,
+	})
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+
+	err = readSSE(resp.Body, func(event, data string) error {
+		var payload struct {
+			Type    string `json:"type"`
+			Message struct {
+				Usage struct {
+					InputTokens int `json:"input_tokens"`
+				} `json:"usage"`
+			} `json:"message"`
+			Delta struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"delta"`
+			Usage struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &payload); err != nil {
+			return nil
+		}
+
+		switch payload.Type {
+		case "message_start":
+			usage.PromptTokens = payload.Message.Usage.InputTokens
+		case "content_block_delta":
+			if payload.Delta.Type == "text_delta" && payload.Delta.Text != "" {
+				return onDelta(payload.Delta.Text)
+			}
+		case "message_delta":
+			usage.CompletionTokens = payload.Usage.OutputTokens
+		case "message_stop":
+			return io.EOF
+		case "error":
+			return errors.New(payload.Error.Type + ": " + payload.Error.Message)
+		}
+		return nil
+	})
+	return usage, err
+}
@@ -0,0 +1,242 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"go_blog/apperr"
+	"go_blog/search"
+	"go_blog/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTooManyTurns 会话的提问次数已达上限
+var ErrTooManyTurns = apperr.New(apperr.Conflict, "too_many_turns", "本次对话的提问次数已达上限，请清空对话后重新开始")
+
+// 问答默认配置
+const (
+	defaultChatMaxTurns      = 10
+	defaultChatHistoryTokens = 2000
+	defaultChatContextTokens = 3000
+	defaultChatQuestionLen   = 500
+	defaultChatSessionTTL    = 30 * time.Minute
+
+	// 文章超出上下文上限时按该大小切分段落，再挑选与问题相关的段落
+	chatPassageTokens = 300
+)
+
+const chatSystemPrompt = `你是博客的阅读助手，负责回答读者关于文章《%s》的问题。
+只根据下面提供的文章内容回答，内容中没有的信息请直接说明文章未提及，不要编造。
+回答使用与问题相同的语言，简洁清晰，可以使用 markdown。%s
+
+文章内容：
+%s`
+
+const chatPartialNote = "\n以下只是文章中与问题最相关的部分段落。"
+
+// ChatSettings 问答配置，未配置的项使用默认值
+type ChatSettings struct {
+	MaxTurns          int
+	HistoryTokens     int
+	ContextTokens     int
+	MaxQuestionLength int
+	SessionTTL        time.Duration
+}
+
+// GetChatSettings 读取问答配置
+func GetChatSettings() ChatSettings {
+	cfg := utils.AppConfig.AI.Chat
+	s := ChatSettings{
+		MaxTurns:          cfg.MaxTurns,
+		HistoryTokens:     cfg.HistoryTokens,
+		ContextTokens:     cfg.ContextTokens,
+		MaxQuestionLength: cfg.MaxQuestionLength,
+		SessionTTL:        time.Duration(cfg.SessionTTL) * time.Minute,
+	}
+	if s.MaxTurns <= 0 {
+		s.MaxTurns = defaultChatMaxTurns
+	}
+	if s.HistoryTokens <= 0 {
+		s.HistoryTokens = defaultChatHistoryTokens
+	}
+	if s.ContextTokens <= 0 {
+		s.ContextTokens = defaultChatContextTokens
+	}
+	if s.MaxQuestionLength <= 0 {
+		s.MaxQuestionLength = defaultChatQuestionLen
+	}
+	if s.SessionTTL <= 0 {
+		s.SessionTTL = defaultChatSessionTTL
+	}
+	return s
+}
+
+// Conversation 一个会话针对一篇文章的对话
+type Conversation struct {
+	Messages []Message
+	Turns    int
+	updated  time.Time
+}
+
+// ChatStore 保存在内存中的对话历史，闲置超时后清除
+type ChatStore struct {
+	mu            sync.Mutex
+	conversations map[string]*Conversation
+	lastCleanup   time.Time
+}
+
+// Chats 全局对话历史
+var Chats = &ChatStore{conversations: make(map[string]*Conversation)}
+
+// Reserve 在提问次数未达到 max 时预留一次提问，返回对话历史的副本和预留后的提问次数。
+// 检查和预留在同一把锁内完成，同一会话的并发提问不会超出上限；
+// 回答完成后调用 Append 记录问答，失败时调用 Release 归还次数
+func (s *ChatStore) Reserve(key string, max int) ([]Message, int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanup()
+
+	conv, ok := s.conversations[key]
+	if !ok {
+		conv = &Conversation{}
+		s.conversations[key] = conv
+	}
+	if conv.Turns >= max {
+		return nil, conv.Turns, false
+	}
+	conv.Turns++
+	conv.updated = time.Now()
+	return append([]Message(nil), conv.Messages...), conv.Turns, true
+}
+
+// Release 归还预留但未完成的提问次数
+func (s *ChatStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conv, ok := s.conversations[key]; ok && conv.Turns > 0 {
+		conv.Turns--
+	}
+}
+
+// Append 记录已预留的一轮问答，历史超出 token 上限时丢弃最早的几轮
+func (s *ChatStore) Append(key, question, answer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 回答期间对话被清空时不再记录
+	conv, ok := s.conversations[key]
+	if !ok {
+		return
+	}
+	conv.Messages = append(conv.Messages,
+		Message{Role: RoleUser, Content: question},
+		Message{Role: RoleAssistant, Content: answer})
+	conv.updated = time.Now()
+
+	limit := GetChatSettings().HistoryTokens
+	for len(conv.Messages) > 2 && messagesTokens(conv.Messages) > limit {
+		conv.Messages = conv.Messages[2:]
+	}
+}
+
+// Reset 清空对话
+func (s *ChatStore) Reset(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conversations, key)
+}
+
+// 清除闲置超时的对话，调用方需持有锁
+func (s *ChatStore) cleanup() {
+	ttl := GetChatSettings().SessionTTL
+	now := time.Now()
+	if now.Sub(s.lastCleanup) < time.Minute {
+		return
+	}
+	for key, conv := range s.conversations {
+		if now.Sub(conv.updated) > ttl {
+			delete(s.conversations, key)
+		}
+	}
+	s.lastCleanup = now
+}
+
+func messagesTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg.Content)
+	}
+	return total
+}
+
+// ChatContext 选取作为参考的文章内容：全文不超过 maxTokens 时使用全文，
+// 否则按与问题的词语重合度挑选段落，并按原文顺序拼接
+func ChatContext(blocks []utils.TextBlock, question string, maxTokens int) (string, bool) {
+	passages := SplitBlocks(blocks, chatPassageTokens, 0)
+	total := 0
+	for _, p := range passages {
+		total += EstimateTokens(p)
+	}
+	if total <= maxTokens {
+		return strings.Join(passages, "\n\n"), false
+	}
+
+	terms := make(map[string]bool)
+	for _, t := range search.Tokenize(question) {
+		terms[t] = true
+	}
+	type scored struct {
+		index int
+		score int
+	}
+	ranked := make([]scored, len(passages))
+	for i, p := range passages {
+		ranked[i].index = i
+		for _, t := range search.Tokenize(p) {
+			if terms[t] {
+				ranked[i].score++
+			}
+		}
+	}
+	// 得分相同时优先靠前的段落，开头通常是文章的背景介绍
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	selected := make([]int, 0, len(ranked))
+	used := 0
+	for _, r := range ranked {
+		tokens := EstimateTokens(passages[r.index])
+		if used+tokens > maxTokens {
+			continue
+		}
+		selected = append(selected, r.index)
+		used += tokens
+	}
+	sort.Ints(selected)
+
+	parts := make([]string, len(selected))
+	for i, idx := range selected {
+		parts[i] = passages[idx]
+	}
+	return strings.Join(parts, "\n\n……\n\n"), true
+}
+
+// Chat 结合文章内容和对话历史回答问题
+func Chat(ctx context.Context, p Provider, title string, blocks []utils.TextBlock, history []Message, question string, onDelta DeltaFunc) (Usage, error) {
+	settings := GetChatSettings()
+	articleContext, partial := ChatContext(blocks, question, settings.ContextTokens)
+	note := ""
+	if partial {
+		note = chatPartialNote
+	}
+
+	messages := append(append([]Message(nil), history...), Message{Role: RoleUser, Content: question})
+	return p.Stream(ctx, Request{
+		System:   fmt.Sprintf(chatSystemPrompt, title, note, articleContext),
+		Messages: messages,
+	}, onDelta)
+}
@@ -0,0 +1,176 @@
+package llm
+
+import (
+	"go_blog/utils"
+	"strings"
+)
+
+// 默认分块参数
+const (
+	defaultChunkTokens  = 3000
+	defaultChunkOverlap = 200
+)
+
+// ChunkSettings 获取模型的分块大小和重叠大小（token），未配置时使用默认值
+func ChunkSettings(model string) (chunkTokens, overlap int) {
+	cfg := modelConfig(model)
+	chunkTokens, overlap = cfg.ChunkTokens, cfg.ChunkOverlap
+	if chunkTokens <= 0 {
+		chunkTokens = defaultChunkTokens
+	}
+	if overlap < 0 || overlap >= chunkTokens {
+		overlap = 0
+	} else if overlap == 0 {
+		overlap = defaultChunkOverlap
+		if overlap >= chunkTokens {
+			overlap = chunkTokens / 10
+		}
+	}
+	return chunkTokens, overlap
+}
+
+// 查找模型配置，未单独配置时使用 default
+func modelConfig(model string) utils.ModelConfig {
+	var cfg utils.ModelConfig
+	for _, m := range utils.AppConfig.AI.Models {
+		if m.Name == model {
+			return m
+		}
+		if m.Name == "default" {
+			cfg = m
+		}
+	}
+	return cfg
+}
+
+// SplitBlocks 按段落和标题边界将文本块切分为不超过 maxTokens 的分块，
+// 相邻分块之间保留约 overlap 个 token 的重叠内容
+func SplitBlocks(blocks []utils.TextBlock, maxTokens, overlap int) []string {
+	var chunks []string
+	var current []string
+	currentTokens := 0
+
+	emit := func() {
+		if len(current) == 0 {
+			return
+		}
+		chunks = append(chunks, strings.Join(current, "\n\n"))
+
+		// 从上一块末尾取重叠内容
+		var tail []string
+		tailTokens := 0
+		for i := len(current) - 1; i >= 0 && tailTokens < overlap; i-- {
+			t := EstimateTokens(current[i])
+			if tailTokens+t > overlap {
+				tail = append([]string{tailRunes(current[i], overlap-tailTokens)}, tail...)
+				tailTokens = overlap
+				break
+			}
+			tail = append([]string{current[i]}, tail...)
+			tailTokens += t
+		}
+		current, currentTokens = tail, tailTokens
+	}
+
+	for _, block := range blocks {
+		text := block.Text
+		if block.Heading {
+			text = "## " + text
+			// 当前块已过半时，在标题处切分，尽量保持章节完整
+			if currentTokens > maxTokens/2 {
+				emit()
+			}
+		}
+
+		for _, piece := range splitOversized(text, maxTokens-overlap) {
+			t := EstimateTokens(piece)
+			if currentTokens+t > maxTokens && currentTokens > overlap {
+				emit()
+			}
+			current = append(current, piece)
+			currentTokens += t
+		}
+	}
+	if currentTokens > overlap || len(chunks) == 0 {
+		emit()
+	}
+	return chunks
+}
+
+// 将超过 maxTokens 的单个文本块按句子切分，没有句子边界时按长度硬切
+func splitOversized(text string, maxTokens int) []string {
+	if maxTokens <= 0 || EstimateTokens(text) <= maxTokens {
+		return []string{text}
+	}
+
+	var pieces []string
+	var b strings.Builder
+	tokens := 0
+	for _, sentence := range splitSentences(text) {
+		t := EstimateTokens(sentence)
+		if tokens+t > maxTokens && b.Len() > 0 {
+			pieces = append(pieces, b.String())
+			b.Reset()
+			tokens = 0
+		}
+		for t > maxTokens {
+			head := headRunes(sentence, maxTokens)
+			pieces = append(pieces, head)
+			sentence = strings.TrimPrefix(sentence, head)
+			t = EstimateTokens(sentence)
+		}
+		b.WriteString(sentence)
+		tokens += t
+	}
+	if b.Len() > 0 {
+		pieces = append(pieces, b.String())
+	}
+	return pieces
+}
+
+// 按中英文句末标点切分句子，标点保留在句尾
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		if strings.ContainsRune("。！？!?；;", r) || (r == '.' && i+1 < len(runes) && runes[i+1] == ' ') {
+			sentences = append(sentences, string(runes[start:i+1]))
+			start = i + 1
+		}
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+	return sentences
+}
+
+// 取开头不超过 tokens 个 token 的内容
+func headRunes(text string, tokens int) string {
+	runes := []rune(text)
+	lo, hi := 1, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if EstimateTokens(string(runes[:mid])) <= tokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo])
+}
+
+// 取末尾不超过 tokens 个 token 的内容
+func tailRunes(text string, tokens int) string {
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if EstimateTokens(string(runes[mid:])) <= tokens {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return string(runes[lo:])
+}
@@ -0,0 +1,280 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_blog/utils"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 单次重试的最长等待时间
+const maxRetryDelay = 30 * time.Second
+
+// ErrCircuitOpen 熔断期间直接拒绝请求
+var ErrCircuitOpen = errors.New("AI 服务暂不可用，请稍后重试")
+
+// APIError 服务商返回的非 200 响应
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%s 返回 %d (%s): %s", e.Provider, e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("%s 返回 %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// 429 和 5xx 可以重试
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// httpClient 带超时、重试和熔断的 HTTP 客户端，每个服务商一个实例
+type httpClient struct {
+	provider string
+	client   *http.Client
+	cfg      utils.ClientConfig
+	breaker  *breaker
+}
+
+// 按配置创建客户端，未配置的项使用默认值
+func newHTTPClient(provider string) *httpClient {
+	cfg := utils.AppConfig.AI.Client
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = 10
+	}
+	if cfg.FirstByteTimeout <= 0 {
+		cfg.FirstByteTimeout = 60
+	}
+	if cfg.TotalTimeout <= 0 {
+		cfg.TotalTimeout = 300
+	}
+	// 默认重试 2 次，配置为负数时不重试
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	} else if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = 5
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = 30
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   time.Duration(cfg.ConnectTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = time.Duration(cfg.ConnectTimeout) * time.Second
+	// 首字节超时：发出请求后等待响应头的最长时间
+	transport.ResponseHeaderTimeout = time.Duration(cfg.FirstByteTimeout) * time.Second
+
+	return &httpClient{
+		provider: provider,
+		// 总超时包含读取整个流式响应的时间
+		client:  &http.Client{Transport: transport, Timeout: time.Duration(cfg.TotalTimeout) * time.Second},
+		cfg:     cfg,
+		breaker: &breaker{threshold: cfg.BreakerThreshold, cooldown: time.Duration(cfg.BreakerCooldown) * time.Second},
+	}
+}
+
+// postJSON 发送 JSON 请求，在开始读取流之前对网络错误、429 和 5xx 进行重试；
+// 返回的响应状态码一定为 200，响应体由调用方关闭
+func (c *httpClient) postJSON(ctx context.Context, url string, body interface{}, headers map[string]string) (*http.Response, error) {
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	log := utils.Log.WithFields(logrus.Fields{"provider": c.provider, "url": url})
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, url, jsonBody, headers)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			c.breaker.success()
+			return resp, nil
+		}
+
+		// 客户端已取消，不计入熔断
+		if ctx.Err() != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			c.breaker.abort()
+			return nil, ctx.Err()
+		}
+
+		var delay time.Duration
+		if err == nil {
+			apiErr := parseAPIError(c.provider, resp)
+			delay = retryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+			log.WithFields(logrus.Fields{
+				"status_code": apiErr.StatusCode,
+				"error_type":  apiErr.Type,
+				"attempt":     attempt + 1,
+			}).Error(apiErr.Message)
+
+			if !retryable(apiErr.StatusCode) {
+				// 4xx 说明请求本身有问题，服务商仍然可用
+				c.breaker.success()
+				return nil, apiErr
+			}
+			err = apiErr
+		} else {
+			log.WithError(err).WithField("attempt", attempt+1).Error("请求 AI 服务失败")
+		}
+
+		// 服务商要求等待的时间过长时不再重试
+		if attempt >= c.cfg.MaxRetries || delay > maxRetryDelay {
+			c.breaker.failure()
+			return nil, err
+		}
+
+		if delay <= 0 {
+			delay = c.backoff(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			c.breaker.abort()
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *httpClient) send(ctx context.Context, url string, body []byte, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return c.client.Do(req)
+}
+
+// 指数退避加随机抖动，最长 maxRetryDelay
+func (c *httpClient) backoff(attempt int) time.Duration {
+	d := time.Duration(c.cfg.RetryBackoff) * time.Millisecond << attempt
+	if d > maxRetryDelay || d <= 0 {
+		d = maxRetryDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// 解析 Retry-After，支持秒数和 HTTP 日期两种格式
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// 解析服务商的错误响应，兼容 OpenAI、Anthropic 和 Ollama 的格式
+func parseAPIError(provider string, resp *http.Response) *APIError {
+	apiErr := &APIError{Provider: provider, StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var payload struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &payload) == nil && len(payload.Error) > 0 {
+		var detail struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		}
+		var message string
+		if json.Unmarshal(payload.Error, &detail) == nil && detail.Message != "" {
+			apiErr.Type = detail.Type
+			apiErr.Message = detail.Message
+		} else if json.Unmarshal(payload.Error, &message) == nil {
+			apiErr.Message = message
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = string(data)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// breaker 熔断器：连续失败达到阈值后打开，冷却期过后放行一个试探请求
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		utils.Log.WithField("cooldown", b.cooldown.String()).Warn("AI 服务连续失败，熔断器已打开")
+	}
+}
+
+// 请求被取消时既不算成功也不算失败，只释放试探名额
+func (b *breaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
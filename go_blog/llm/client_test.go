@@ -0,0 +1,293 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"go_blog/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// 测试中不写日志文件
+	utils.Log = logrus.New()
+	utils.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// 创建指向测试服务器的客户端，退避时间缩短为 1 毫秒
+func newTestClient(t *testing.T, cfg utils.ClientConfig) *httpClient {
+	t.Helper()
+	old := utils.AppConfig.AI.Client
+	t.Cleanup(func() { utils.AppConfig.AI.Client = old })
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = 1
+	}
+	utils.AppConfig.AI.Client = cfg
+	return newHTTPClient("test")
+}
+
+// 按顺序返回预设响应的测试服务器，超出后重复最后一个
+type step struct {
+	status     int
+	retryAfter string
+	body       string
+	delay      time.Duration
+}
+
+func newTestServer(t *testing.T, steps ...step) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&hits, 1)) - 1
+		if n >= len(steps) {
+			n = len(steps) - 1
+		}
+		s := steps[n]
+		if s.delay > 0 {
+			select {
+			case <-time.After(s.delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if s.retryAfter != "" {
+			w.Header().Set("Retry-After", s.retryAfter)
+		}
+		w.WriteHeader(s.status)
+		io.WriteString(w, s.body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func post(c *httpClient, url string) error {
+	resp, err := c.postJSON(context.Background(), url, map[string]string{"q": "hi"}, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func TestPostJSONRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        utils.ClientConfig
+		steps      []step
+		wantStatus int // 0 表示成功
+		wantHits   int32
+		minElapsed time.Duration
+		maxElapsed time.Duration
+	}{
+		{
+			name:     "首次成功",
+			steps:    []step{{status: 200}},
+			wantHits: 1,
+		},
+		{
+			name:       "429 按 Retry-After 等待后重试",
+			steps:      []step{{status: 429, retryAfter: "1"}, {status: 200}},
+			wantHits:   2,
+			minElapsed: time.Second,
+		},
+		{
+			name:       "Retry-After 过长时不再重试",
+			steps:      []step{{status: 429, retryAfter: "120"}},
+			wantStatus: 429,
+			wantHits:   1,
+			maxElapsed: time.Second,
+		},
+		{
+			name:       "5xx 重试次数用完后返回错误",
+			cfg:        utils.ClientConfig{MaxRetries: 2},
+			steps:      []step{{status: 503, body: `{"error":{"type":"server_error","message":"busy"}}`}},
+			wantStatus: 503,
+			wantHits:   3,
+		},
+		{
+			name:     "5xx 之后成功",
+			steps:    []step{{status: 500}, {status: 502}, {status: 200}},
+			wantHits: 3,
+		},
+		{
+			name:       "4xx 不重试",
+			steps:      []step{{status: 400, body: `{"error":{"message":"bad"}}`}},
+			wantStatus: 400,
+			wantHits:   1,
+		},
+		{
+			name:       "配置为负数时不重试",
+			cfg:        utils.ClientConfig{MaxRetries: -1},
+			steps:      []step{{status: 500}},
+			wantStatus: 500,
+			wantHits:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, hits := newTestServer(t, tt.steps...)
+			c := newTestClient(t, tt.cfg)
+
+			start := time.Now()
+			err := post(c, srv.URL)
+			elapsed := time.Since(start)
+
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("期望成功，得到 %v", err)
+				}
+			} else {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.wantStatus {
+					t.Fatalf("期望状态码 %d，得到 %v", tt.wantStatus, err)
+				}
+			}
+			if got := atomic.LoadInt32(hits); got != tt.wantHits {
+				t.Errorf("请求次数 = %d，期望 %d", got, tt.wantHits)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("耗时 %v，期望至少 %v", elapsed, tt.minElapsed)
+			}
+			if tt.maxElapsed > 0 && elapsed > tt.maxElapsed {
+				t.Errorf("耗时 %v，期望不超过 %v", elapsed, tt.maxElapsed)
+			}
+		})
+	}
+}
+
+func TestPostJSONFirstByteTimeout(t *testing.T) {
+	srv, hits := newTestServer(t, step{status: 200, delay: time.Second})
+	c := newTestClient(t, utils.ClientConfig{MaxRetries: -1})
+	c.client.Transport.(*http.Transport).ResponseHeaderTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	err := post(c, srv.URL)
+	if err == nil {
+		t.Fatal("期望首字节超时")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("超时后仍等待了 %v", elapsed)
+	}
+	if got := atomic.LoadInt32(hits); got != 1 {
+		t.Errorf("请求次数 = %d，期望 1", got)
+	}
+}
+
+func TestPostJSONBreaker(t *testing.T) {
+	srv, hits := newTestServer(t, step{status: 500}, step{status: 500}, step{status: 200})
+	c := newTestClient(t, utils.ClientConfig{MaxRetries: -1, BreakerThreshold: 2})
+	c.breaker.cooldown = 100 * time.Millisecond
+
+	// 连续失败达到阈值后打开
+	for i := 0; i < 2; i++ {
+		if err := post(c, srv.URL); err == nil {
+			t.Fatalf("第 %d 次请求期望失败", i+1)
+		}
+	}
+	if err := post(c, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("熔断期间期望 ErrCircuitOpen，得到 %v", err)
+	}
+	if got := atomic.LoadInt32(hits); got != 2 {
+		t.Fatalf("熔断期间不应请求服务商，请求次数 = %d", got)
+	}
+
+	// 冷却后放行一个试探请求，成功后关闭
+	time.Sleep(150 * time.Millisecond)
+	if err := post(c, srv.URL); err != nil {
+		t.Fatalf("试探请求期望成功，得到 %v", err)
+	}
+	if err := post(c, srv.URL); err != nil {
+		t.Fatalf("熔断器关闭后期望成功，得到 %v", err)
+	}
+	if got := atomic.LoadInt32(hits); got != 4 {
+		t.Errorf("请求次数 = %d，期望 4", got)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: 50 * time.Millisecond}
+	b.failure()
+	if b.allow() {
+		t.Fatal("冷却期内应拒绝请求")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("冷却后应放行试探请求")
+	}
+	if b.allow() {
+		t.Fatal("试探期间只放行一个请求")
+	}
+
+	// 试探失败重新打开
+	b.failure()
+	if b.allow() {
+		t.Fatal("试探失败后应重新熔断")
+	}
+
+	// 试探被取消时释放名额
+	time.Sleep(60 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("冷却后应放行试探请求")
+	}
+	b.abort()
+	if !b.allow() {
+		t.Fatal("试探取消后应允许再次试探")
+	}
+	b.success()
+	if !b.allow() || !b.allow() {
+		t.Fatal("试探成功后应关闭熔断器")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", 0, 0},
+		{"3", 3 * time.Second, 3 * time.Second},
+		{"abc", 0, 0},
+		{time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("retryAfter(%q) = %v，期望在 [%v, %v] 之间", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantType    string
+		wantMessage string
+	}{
+		{"OpenAI", 429, `{"error":{"message":"Rate limit reached","type":"requests"}}`, "requests", "Rate limit reached"},
+		{"Anthropic", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "overloaded_error", "Overloaded"},
+		{"Ollama", 404, `{"error":"model not found"}`, "", "model not found"},
+		{"纯文本", 502, "Bad Gateway from proxy", "", "Bad Gateway from proxy"},
+		{"空响应", 503, "", "", "Service Unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			got := parseAPIError("test", resp)
+			if got.StatusCode != tt.status || got.Type != tt.wantType || got.Message != tt.wantMessage {
+				t.Errorf("parseAPIError = %+v，期望 type=%q message=%q", got, tt.wantType, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go_blog/search"
+	"go_blog/utils"
+	"hash/fnv"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// 本地向量的默认维度
+const defaultLocalDimensions = 256
+
+// Embedder 文本向量服务
+type Embedder interface {
+	// Name 服务商名称
+	Name() string
+	// Model 使用的向量模型
+	Model() string
+	// Embed 计算一批文本的向量，返回的向量与输入一一对应
+	Embed(ctx context.Context, texts []string) ([][]float32, Usage, error)
+}
+
+// DefaultEmbedder 根据配置创建的向量服务
+var DefaultEmbedder Embedder
+
+// InitEmbedder 根据配置初始化向量服务
+func InitEmbedder() error {
+	name := strings.ToLower(utils.AppConfig.AI.Embedding.Provider)
+	if name == "" {
+		// 跟随 AI 服务商，不提供向量接口的服务商使用本地向量
+		name = strings.ToLower(utils.AppConfig.AI.Provider)
+		if os.Getenv("OPENAI_ENV") == "DEV" {
+			name = "local"
+		}
+		switch name {
+		case "", "openai", "ollama":
+		default:
+			name = "local"
+		}
+	}
+	e, err := NewEmbedder(name)
+	if err != nil {
+		return err
+	}
+	DefaultEmbedder = e
+	return nil
+}
+
+// NewEmbedder 按名称创建向量服务，未配置的地址和密钥从对应的 AI 服务商配置推导
+func NewEmbedder(name string) (Embedder, error) {
+	cfg := utils.AppConfig.AI.Embedding
+	if name == "" {
+		name = "openai"
+	}
+
+	var e Embedder
+	switch name {
+	case "openai":
+		provider := providerConfig("openai")
+		if cfg.Url == "" {
+			cfg.Url = "https://api.openai.com/v1/embeddings"
+			if provider.Url != "" {
+				cfg.Url = strings.Replace(provider.Url, "/chat/completions", "/embeddings", 1)
+			}
+		}
+		if cfg.ApiKey == "" {
+			cfg.ApiKey = provider.ApiKey
+		}
+		if cfg.Model == "" {
+			cfg.Model = "text-embedding-3-small"
+		}
+		e = &OpenAIEmbedder{cfg: cfg, client: newHTTPClient("openai")}
+	case "ollama":
+		if cfg.Url == "" {
+			cfg.Url = "http://localhost:11434/api/embed"
+			if provider := providerConfig("ollama"); provider.Url != "" {
+				cfg.Url = strings.Replace(provider.Url, "/api/generate", "/api/embed", 1)
+			}
+		}
+		if cfg.Model == "" {
+			cfg.Model = "nomic-embed-text"
+		}
+		e = &OllamaEmbedder{cfg: cfg, client: newHTTPClient("ollama")}
+	case "local":
+		return NewLocalEmbedder(cfg.Dimensions), nil
+	default:
+		return nil, fmt.Errorf("未知的向量服务: %s", name)
+	}
+	return &meteredEmbedder{Embedder: e}, nil
+}
+
+// OpenAIEmbedder OpenAI 兼容的 embeddings 接口
+type OpenAIEmbedder struct {
+	cfg    utils.EmbeddingConfig
+	client *httpClient
+}
+
+func (e *OpenAIEmbedder) Name() string  { return "openai" }
+func (e *OpenAIEmbedder) Model() string { return e.cfg.Model }
+
+// Embed 调用 embeddings 接口
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, Usage, error) {
+	resp, err := e.client.postJSON(ctx, e.cfg.Url, map[string]interface{}{
+		"model": e.cfg.Model,
+		"input": texts,
+	}, map[string]string{
+		"Authorization": "Bearer " + e.cfg.ApiKey,
+	})
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, Usage{}, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	return vectors, Usage{PromptTokens: result.Usage.PromptTokens}, checkVectors(vectors)
+}
+
+// OllamaEmbedder Ollama 的 /api/embed 接口
+type OllamaEmbedder struct {
+	cfg    utils.EmbeddingConfig
+	client *httpClient
+}
+
+func (e *OllamaEmbedder) Name() string  { return "ollama" }
+func (e *OllamaEmbedder) Model() string { return e.cfg.Model }
+
+// Embed 调用 /api/embed 接口
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, Usage, error) {
+	resp, err := e.client.postJSON(ctx, e.cfg.Url, map[string]interface{}{
+		"model": e.cfg.Model,
+		"input": texts,
+	}, nil)
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, Usage{}, err
+	}
+	vectors := make([][]float32, len(texts))
+	copy(vectors, result.Embeddings)
+	return vectors, Usage{PromptTokens: result.PromptEvalCount}, checkVectors(vectors)
+}
+
+// 校验服务商返回了每条文本的向量
+func checkVectors(vectors [][]float32) error {
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("向量服务未返回第 %d 条文本的向量", i+1)
+		}
+	}
+	return nil
+}
+
+// LocalEmbedder 本地确定性向量：对分词结果做特征哈希，不依赖外部服务，
+// 只能反映词语重合程度，适合开发、测试和没有向量接口的服务商
+type LocalEmbedder struct {
+	dimensions int
+}
+
+// NewLocalEmbedder 创建本地向量服务
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultLocalDimensions
+	}
+	return &LocalEmbedder{dimensions: dimensions}
+}
+
+func (e *LocalEmbedder) Name() string  { return "local" }
+func (e *LocalEmbedder) Model() string { return fmt.Sprintf("hash-%d", e.dimensions) }
+
+// Embed 计算词频特征哈希向量并归一化
+func (e *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, Usage, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		counts := make(map[string]int)
+		for _, token := range search.Tokenize(text) {
+			counts[token]++
+		}
+
+		v := make([]float32, e.dimensions)
+		for token, n := range counts {
+			h := fnv.New64a()
+			h.Write([]byte(token))
+			sum := h.Sum64()
+			weight := float32(1 + math.Log(float64(n)))
+			// 用哈希的最高位决定符号，减少不同词语落在同一维度时的相互抵消偏差
+			if sum>>63 == 1 {
+				weight = -weight
+			}
+			v[sum%uint64(e.dimensions)] += weight
+		}
+		Normalize(v)
+		vectors[i] = v
+	}
+	return vectors, Usage{}, ctx.Err()
+}
+
+// Normalize 将向量归一化为单位长度，零向量保持不变
+func Normalize(v []float32) {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range v {
+		v[i] *= scale
+	}
+}
+
+// meteredEmbedder 记录每次调用用量的向量服务包装
+type meteredEmbedder struct {
+	Embedder
+}
+
+// Embed 调用向量服务并记录用量，服务商未返回用量时在本地估算
+func (m *meteredEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, Usage, error) {
+	promptTokens := 0
+	for _, text := range texts {
+		promptTokens += EstimateTokens(text)
+	}
+	release, err := reserveBudget(Cost(m.Model(), Usage{PromptTokens: promptTokens}))
+	if err != nil {
+		return nil, Usage{}, err
+	}
+	defer release()
+
+	start := time.Now()
+	vectors, usage, err := m.Embedder.Embed(ctx, texts)
+	estimated := false
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = promptTokens
+		estimated = true
+	}
+	recordUsage("embedding", 0, m.Name(), m.Model(), usage, estimated, start, err)
+	return vectors, usage, err
+}
@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// Fake 返回固定内容的服务商，用于开发和测试，不发起任何网络请求
+type Fake struct {
+	model  string
+	Chunks []string
+}
+
+// NewFake 创建模拟服务商
+func NewFake(model string) *Fake {
+	if model == "" {
+		model = "fake"
+	}
+	return &Fake{
+		model:  model,
+		Chunks: []string{"这是一个", "测试摘要", "。这篇文章", "主要讨论", "了某个", "技术主题", "。"},
+	}
+}
+
+func (p *Fake) Name() string  { return "fake" }
+func (p *Fake) Model() string { return p.model }
+
+// Stream 依次输出预设的文本片段，用量按字符数计算，结果完全确定
+func (p *Fake) Stream(ctx context.Context, req Request, onDelta DeltaFunc) (Usage, error) {
+	usage := Usage{PromptTokens: utf8.RuneCountInString(req.System)}
+	for _, m := range req.Messages {
+		usage.PromptTokens += utf8.RuneCountInString(m.Content)
+	}
+
+	for _, chunk := range p.Chunks {
+		if err := ctx.Err(); err != nil {
+			return usage, err
+		}
+		if err := onDelta(chunk); err != nil {
+			return usage, err
+		}
+		usage.CompletionTokens += utf8.RuneCountInString(chunk)
+	}
+	return usage, nil
+}
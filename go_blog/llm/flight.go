@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// Event 生成过程中产生的事件，由同一次生成的所有订阅者共享
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// EmitFunc 发布一个事件给所有订阅者
+type EmitFunc func(Event)
+
+// Flight 一次正在进行的生成，事件会保留到结束，后加入的订阅者先回放已产生的事件
+type Flight struct {
+	mu          sync.Mutex
+	events      []Event
+	notify      chan struct{} // 每次有新事件或结束时关闭并替换
+	done        bool
+	err         error
+	subscribers int
+	canceled    bool
+	cancel      context.CancelFunc
+}
+
+// FlightGroup 合并相同键的并发生成：同一时刻只发起一次上游调用，结果分发给所有等待的客户端
+type FlightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*Flight
+}
+
+// Join 加入键对应的生成，没有进行中的生成时用 fn 发起一次。
+// fn 的 context 与单个请求无关，只有在所有订阅者都离开后才会取消
+func (g *FlightGroup) Join(key string, fn func(ctx context.Context, emit EmitFunc) error) (*Flight, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if f, ok := g.flights[key]; ok && f.join() {
+		return f, false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Flight{notify: make(chan struct{}), subscribers: 1, cancel: cancel}
+	if g.flights == nil {
+		g.flights = make(map[string]*Flight)
+	}
+	g.flights[key] = f
+
+	go func() {
+		defer cancel()
+		err := fn(ctx, f.publish)
+
+		g.mu.Lock()
+		if g.flights[key] == f {
+			delete(g.flights, key)
+		}
+		g.mu.Unlock()
+		f.finish(err)
+	}()
+	return f, true
+}
+
+// 增加一个订阅者，已被取消的生成不能再加入
+func (f *Flight) join() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.canceled {
+		return false
+	}
+	f.subscribers++
+	return true
+}
+
+// 订阅者离开，最后一个离开且生成未结束时取消上游调用
+func (f *Flight) leave() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribers--
+	if f.subscribers == 0 && !f.done {
+		f.canceled = true
+		f.cancel()
+	}
+}
+
+func (f *Flight) publish(ev Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, ev)
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+func (f *Flight) finish(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.done = true
+	f.err = err
+	close(f.notify)
+}
+
+// Subscribe 依次将事件交给 fn，直到生成结束、fn 返回错误或 ctx 取消，返回生成的错误。
+// 每个 Join 都必须对应一次 Subscribe
+func (f *Flight) Subscribe(ctx context.Context, fn func(Event) error) error {
+	defer f.leave()
+
+	next := 0
+	for {
+		f.mu.Lock()
+		pending := f.events[next:]
+		next = len(f.events)
+		done, err, notify := f.done, f.err, f.notify
+		f.mu.Unlock()
+
+		for _, ev := range pending {
+			if e := fn(ev); e != nil {
+				return e
+			}
+		}
+		if done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notify:
+		}
+	}
+}
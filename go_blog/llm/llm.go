@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"go_blog/utils"
+	"os"
+	"strings"
+)
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// 消息角色
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Request 生成请求
+type Request struct {
+	// System 系统提示词，可为空
+	System string
+	// Messages 对话消息，至少包含一条用户消息
+	Messages []Message
+}
+
+// Usage 一次调用的 token 用量，服务商未返回时为 0
+type Usage struct {
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+}
+
+// DeltaFunc 接收流式输出的增量文本，返回错误时中止生成
+type DeltaFunc func(delta string) error
+
+// Provider 大模型服务商
+type Provider interface {
+	// Name 服务商名称
+	Name() string
+	// Model 使用的模型
+	Model() string
+	// Stream 流式生成，每收到一段文本调用一次 onDelta
+	Stream(ctx context.Context, req Request, onDelta DeltaFunc) (Usage, error)
+}
+
+// Default 根据配置创建的服务商
+var Default Provider
+
+// Init 根据配置初始化服务商和向量服务，并加载提示词模板
+func Init() error {
+	if err := LoadPrompts(); err != nil {
+		return err
+	}
+
+	name := utils.AppConfig.AI.Provider
+	// 兼容旧的 OPENAI_ENV=DEV 开关
+	if os.Getenv("OPENAI_ENV") == "DEV" {
+		name = "fake"
+	}
+	p, err := New(name)
+	if err != nil {
+		return err
+	}
+	Default = p
+	return InitEmbedder()
+}
+
+// New 按名称创建服务商，配置从 AI.Providers 中读取
+func New(name string) (Provider, error) {
+	name = strings.ToLower(name)
+	if name == "" {
+		name = "openai"
+	}
+	cfg := providerConfig(name)
+
+	switch name {
+	case "openai":
+		return NewOpenAI(cfg), nil
+	case "ollama":
+		return NewOllama(cfg), nil
+	case "anthropic":
+		return NewAnthropic(cfg), nil
+	case "fake":
+		return NewFake(cfg.Model), nil
+	default:
+		return nil, fmt.Errorf("未知的 AI 服务商: %s", name)
+	}
+}
+
+// 读取服务商配置，未配置的字段使用 AI 下的通用配置
+func providerConfig(name string) utils.ProviderConfig {
+	ai := utils.AppConfig.AI
+	cfg := ai.Providers[name]
+	if cfg.Model == "" {
+		cfg.Model = ai.Model
+	}
+	// 旧配置中的 apiKey 和 url 用于 OpenAI 兼容接口
+	if name == "openai" {
+		if cfg.Url == "" {
+			cfg.Url = ai.Url
+		}
+		if cfg.ApiKey == "" {
+			cfg.ApiKey = ai.ApiKey
+		}
+	}
+	return cfg
+}
+
+// CacheKey 标识服务商和模型，用于缓存等场景
+func CacheKey(p Provider) string {
+	return p.Name() + ":" + p.Model()
+}
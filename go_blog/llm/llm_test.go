@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"go_blog/utils"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	old := utils.AppConfig.AI
+	t.Cleanup(func() { utils.AppConfig.AI = old })
+	utils.AppConfig.AI.Model = "gpt-4o-mini"
+	utils.AppConfig.AI.Providers = map[string]utils.ProviderConfig{
+		"ollama": {Model: "qwen2.5"},
+	}
+
+	tests := []struct {
+		name      string
+		wantName  string
+		wantModel string
+		wantErr   bool
+	}{
+		{"", "openai", "gpt-4o-mini", false},
+		{"openai", "openai", "gpt-4o-mini", false},
+		{"Ollama", "ollama", "qwen2.5", false},
+		{"anthropic", "anthropic", "gpt-4o-mini", false},
+		{"fake", "fake", "gpt-4o-mini", false},
+		{"unknown", "", "", true},
+	}
+	for _, tt := range tests {
+		p, err := New(tt.name)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("New(%q) 期望返回错误", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("New(%q) 返回错误: %v", tt.name, err)
+			continue
+		}
+		if p.Name() != tt.wantName || p.Model() != tt.wantModel {
+			t.Errorf("New(%q) = %s/%s，期望 %s/%s", tt.name, p.Name(), p.Model(), tt.wantName, tt.wantModel)
+		}
+	}
+}
+
+func TestFakeStream(t *testing.T) {
+	p := NewFake("")
+	req := Request{System: "系统", Messages: []Message{{Role: RoleUser, Content: "你好"}}}
+
+	var out strings.Builder
+	usage, err := p.Stream(context.Background(), req, func(delta string) error {
+		out.WriteString(delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != strings.Join(p.Chunks, "") {
+		t.Errorf("输出 = %q", out.String())
+	}
+	if usage.PromptTokens != 4 || usage.CompletionTokens != len([]rune(out.String())) {
+		t.Errorf("用量 = %+v", usage)
+	}
+
+	// 取消后停止输出
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Stream(ctx, req, func(string) error { return nil }); err == nil {
+		t.Error("取消后期望返回错误")
+	}
+}
+
+func TestChatStoreReserve(t *testing.T) {
+	s := &ChatStore{conversations: make(map[string]*Conversation)}
+	const max = 3
+
+	// 并发提问不会超出上限
+	var wg sync.WaitGroup
+	var granted int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, ok := s.Reserve("k", max); ok {
+				atomic.AddInt32(&granted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if granted != max {
+		t.Fatalf("预留成功 %d 次，期望 %d", granted, max)
+	}
+
+	// 失败的提问归还次数
+	s.Release("k")
+	history, turns, ok := s.Reserve("k", max)
+	if !ok || turns != max || len(history) != 0 {
+		t.Fatalf("归还后 Reserve = %v, %d, %v", history, turns, ok)
+	}
+
+	s.Append("k", "问题", "回答")
+	s.Reset("k")
+	history, turns, ok = s.Reserve("k", max)
+	if !ok || turns != 1 || len(history) != 0 {
+		t.Errorf("清空后 Reserve = %v, %d, %v", history, turns, ok)
+	}
+	s.Append("k", "问题", "回答")
+	if history, _, _ = s.Reserve("k", max); len(history) != 2 {
+		t.Errorf("历史 = %v，期望一轮问答", history)
+	}
+}
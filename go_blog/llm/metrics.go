@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"fmt"
+	"go_blog/models"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// 指标标签：功能、服务商、模型、调用结果
+type metricKey struct {
+	feature, provider, model, status string
+}
+
+type metricValue struct {
+	calls            int64
+	promptTokens     int64
+	completionTokens int64
+	cost             float64
+	latencySeconds   float64
+}
+
+// 进程内的 AI 调用指标，以 Prometheus 文本格式输出
+type usageMetrics struct {
+	mu     sync.Mutex
+	values map[metricKey]*metricValue
+}
+
+var metrics = &usageMetrics{values: make(map[metricKey]*metricValue)}
+
+func (m *usageMetrics) observe(u *models.AIUsage) {
+	status := "success"
+	if !u.Success {
+		status = "error"
+	}
+	key := metricKey{u.Feature, u.Provider, u.Model, status}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		v = &metricValue{}
+		m.values[key] = v
+	}
+	v.calls++
+	v.promptTokens += int64(u.PromptTokens)
+	v.completionTokens += int64(u.CompletionTokens)
+	v.cost += u.Cost
+	v.latencySeconds += float64(u.LatencyMs) / 1000
+}
+
+// WriteMetrics 以 Prometheus 文本格式输出 AI 调用指标
+func WriteMetrics(w io.Writer) {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	keys := make([]metricKey, 0, len(metrics.values))
+	for k := range metrics.values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	labels := func(k metricKey) string {
+		return fmt.Sprintf(`feature=%s,provider=%s,model=%s,status=%s`,
+			strconv.Quote(k.feature), strconv.Quote(k.provider), strconv.Quote(k.model), strconv.Quote(k.status))
+	}
+	write := func(name, typ, help string, value func(*metricValue) string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s{%s} %s\n", name, labels(k), value(metrics.values[k]))
+		}
+	}
+
+	write("blog_ai_requests_total", "counter", "AI 调用次数", func(v *metricValue) string {
+		return strconv.FormatInt(v.calls, 10)
+	})
+	write("blog_ai_prompt_tokens_total", "counter", "输入 token 数", func(v *metricValue) string {
+		return strconv.FormatInt(v.promptTokens, 10)
+	})
+	write("blog_ai_completion_tokens_total", "counter", "输出 token 数", func(v *metricValue) string {
+		return strconv.FormatInt(v.completionTokens, 10)
+	})
+	write("blog_ai_cost_total", "counter", "费用", func(v *metricValue) string {
+		return strconv.FormatFloat(v.cost, 'f', -1, 64)
+	})
+	write("blog_ai_latency_seconds_total", "counter", "调用耗时总和（秒）", func(v *metricValue) string {
+		return strconv.FormatFloat(v.latencySeconds, 'f', -1, 64)
+	})
+}
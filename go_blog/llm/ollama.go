@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"go_blog/utils"
+	"io"
+	"strings"
+)
+
+// Ollama 本地 Ollama 的 /api/generate 接口
+type Ollama struct {
+	cfg    utils.ProviderConfig
+	client *httpClient
+}
+
+// NewOllama 创建 Ollama 服务商
+func NewOllama(cfg utils.ProviderConfig) *Ollama {
+	if cfg.Url == "" {
+		cfg.Url = "http://localhost:11434/api/generate"
+	}
+	return &Ollama{cfg: cfg, client: newHTTPClient("ollama")}
+}
+
+func (p *Ollama) Name() string  { return "ollama" }
+func (p *Ollama) Model() string { return p.cfg.Model }
+
+// Stream 调用 /api/generate 接口并解析逐行 JSON 流
+func (p *Ollama) Stream(ctx context.Context, req Request, onDelta DeltaFunc) (Usage, error) {
+	var usage Usage
+
+	body := map[string]interface{}{
+		"model":  p.cfg.Model,
+		"prompt": flattenMessages(req.Messages),
+		"stream": true,
+	}
+	if req.System != "" {
+		body["system"] = req.System
+	}
+	if p.cfg.MaxTokens > 0 {
+		body["options"] = map[string]int{"num_predict": p.cfg.MaxTokens}
+	}
+
+	resp, err := p.client.postJSON(ctx, p.cfg.Url, body, nil)
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+
+	err = readLines(resp.Body, func(line string) error {
+		var chunk struct {
+			Response        string `json:"response"`
+			Done            bool   `json:"done"`
+			PromptEvalCount int    `json:"prompt_eval_count"`
+			EvalCount       int    `json:"eval_count"`
+		}
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return nil
+		}
+
+		if chunk.Response != "" {
+			if err := onDelta(chunk.Response); err != nil {
+				return err
+			}
+		}
+		if chunk.Done {
+			usage.PromptTokens = chunk.PromptEvalCount
+			usage.CompletionTokens = chunk.EvalCount
+			return io.EOF
+		}
+		return nil
+	})
+	return usage, err
+}
+
+// 将多轮对话拼接为单个提示词，单条用户消息时原样返回
+func flattenMessages(messages []Message) string {
+	if len(messages) == 1 && messages[0].Role == RoleUser {
+		return messages[0].Content
+	}
+	var b strings.Builder
+	for _, m := range messages {
+		b.WriteString(m.Role + ": " + m.Content + "\n\n")
+	}
+	b.WriteString(RoleAssistant + ": ")
+	return b.String()
+}
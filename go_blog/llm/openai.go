@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"go_blog/utils"
+	"io"
+)
+
+// OpenAI OpenAI 兼容的 chat/completions 接口
+type OpenAI struct {
+	cfg    utils.ProviderConfig
+	client *httpClient
+}
+
+// NewOpenAI 创建 OpenAI 兼容服务商
+func NewOpenAI(cfg utils.ProviderConfig) *OpenAI {
+	if cfg.Url == "" {
+		cfg.Url = "https://api.openai.com/v1/chat/completions"
+	}
+	return &OpenAI{cfg: cfg, client: newHTTPClient("openai")}
+}
+
+func (p *OpenAI) Name() string  { return "openai" }
+func (p *OpenAI) Model() string { return p.cfg.Model }
+
+// Stream 调用 chat/completions 接口并解析 SSE 流
+func (p *OpenAI) Stream(ctx context.Context, req Request, onDelta DeltaFunc) (Usage, error) {
+	var usage Usage
+
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+
+	body := map[string]interface{}{
+		"model":    p.cfg.Model,
+		"messages": messages,
+		"stream":   true,
+		"stream_options": map[string]bool{
+			"include_usage": true,
+		},
+	}
+	if p.cfg.MaxTokens > 0 {
+		body["max_tokens"] = p.cfg.MaxTokens
+	}
+
+	resp, err := p.client.postJSON(ctx, p.cfg.Url, body, map[string]string{
+		"Authorization": "Bearer " + p.cfg.ApiKey,
+	})
+	if err != nil {
+		return usage, err
+	}
+	defer resp.Body.Close()
+
+	err = readSSE(resp.Body, func(_, data string) error {
+		if data == "[DONE]" {
+			return io.EOF
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil
+		}
+
+		if chunk.Usage != nil {
+			usage.PromptTokens = chunk.Usage.PromptTokens
+			usage.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			return onDelta(chunk.Choices[0].Delta.Content)
+		}
+		return nil
+	})
+	return usage, err
+}
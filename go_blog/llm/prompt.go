@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go_blog/utils"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrUnknownStyle 请求了未配置的摘要风格
+var ErrUnknownStyle = errors.New("未知的摘要风格")
+
+// PromptData 提示词模板可用的字段
+type PromptData struct {
+	Title       string
+	Category    string
+	PublishTime time.Time
+	// Length 正文纯文本的字符数
+	Length int
+	// Tokens 正文估算的 token 数
+	Tokens int
+}
+
+// 默认的摘要风格，可在配置中覆盖或新增
+var defaultStyles = map[string]string{
+	"brief":               "请用两三句话概括《{{ .Title }}》的核心内容：",
+	"bullet-points":       "请使用markdown无序列表列出《{{ .Title }}》的要点，每条不超过一句话：",
+	"explain-like-im-new": "请用通俗易懂的语言，向刚接触{{ if .Category }} {{ .Category }} {{ end }}的新手解释《{{ .Title }}》讲了什么，避免使用未解释的术语：",
+}
+
+var (
+	defaultPrompt   *template.Template
+	categoryPrompts map[string]*template.Template
+	stylePrompts    map[string]*template.Template
+)
+
+// LoadPrompts 解析并校验所有提示词模板
+func LoadPrompts() error {
+	ai := utils.AppConfig.AI
+
+	prompt := ai.Prompt
+	if prompt == "" {
+		prompt = "请使用markdown格式简要总结以下文章的主要内容，提取重点："
+	}
+
+	var err error
+	if defaultPrompt, err = parsePrompt("prompt", prompt); err != nil {
+		return err
+	}
+
+	categoryPrompts = make(map[string]*template.Template)
+	for category, text := range ai.CategoryPrompts {
+		t, err := parsePrompt("categoryPrompts."+category, text)
+		if err != nil {
+			return err
+		}
+		categoryPrompts[strings.ToLower(category)] = t
+	}
+
+	stylePrompts = make(map[string]*template.Template)
+	styles := make(map[string]string, len(defaultStyles)+len(ai.Styles))
+	for name, text := range defaultStyles {
+		styles[name] = text
+	}
+	for name, text := range ai.Styles {
+		styles[strings.ToLower(name)] = text
+	}
+	for name, text := range styles {
+		t, err := parsePrompt("styles."+name, text)
+		if err != nil {
+			return err
+		}
+		stylePrompts[name] = t
+	}
+	return nil
+}
+
+// 解析模板并使用示例数据试渲染，提前发现字段名错误
+func parsePrompt(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("提示词模板 %s 解析失败: %w", name, err)
+	}
+	sample := PromptData{Title: "示例", Category: "示例", PublishTime: time.Now(), Length: 1, Tokens: 1}
+	if err := t.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, fmt.Errorf("提示词模板 %s 渲染失败: %w", name, err)
+	}
+	return t, nil
+}
+
+// RenderPrompt 渲染摘要提示词：指定风格时使用风格模板，否则优先使用文章分类的模板
+func RenderPrompt(style string, data PromptData) (string, error) {
+	t := defaultPrompt
+	if style != "" {
+		var ok bool
+		if t, ok = stylePrompts[strings.ToLower(style)]; !ok {
+			return "", ErrUnknownStyle
+		}
+	} else if ct, ok := categoryPrompts[strings.ToLower(data.Category)]; ok {
+		t = ct
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// StyleNames 返回所有可用的摘要风格
+func StyleNames() []string {
+	names := make([]string, 0, len(stylePrompts))
+	for name := range stylePrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
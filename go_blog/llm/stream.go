@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// 逐行读取响应，直到 fn 在收到结束标记时返回 io.EOF；
+// 连接在此之前断开视为响应不完整，返回 io.ErrUnexpectedEOF
+func readLines(r io.Reader, fn func(line string) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			if fnErr := fn(line); fnErr != nil {
+				if fnErr == io.EOF {
+					return nil
+				}
+				return fnErr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+// 读取 SSE 响应，按事件回调事件名和 data 内容，fn 在收到结束事件时返回 io.EOF
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	event := ""
+	return readLines(r, func(line string) error {
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			err := fn(event, data)
+			event = ""
+			return err
+		}
+		return nil
+	})
+}
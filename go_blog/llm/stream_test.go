@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"go_blog/utils"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// 创建指向测试服务器的服务商
+func newTestProvider(t *testing.T, name, url string) Provider {
+	t.Helper()
+	client := newTestClient(t, utils.ClientConfig{})
+	cfg := utils.ProviderConfig{Url: url, Model: "test", MaxTokens: 100}
+	switch name {
+	case "openai":
+		p := NewOpenAI(cfg)
+		p.client = client
+		return p
+	case "anthropic":
+		p := NewAnthropic(cfg)
+		p.client = client
+		return p
+	case "ollama":
+		p := NewOllama(cfg)
+		p.client = client
+		return p
+	}
+	t.Fatalf("未知的服务商 %s", name)
+	return nil
+}
+
+func TestStreamRequiresTerminalEvent(t *testing.T) {
+	openaiChunk := `data: {"choices":[{"delta":{"content":"你好"}}]}` + "\n\n"
+	anthropicChunk := "event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"你好"}}` + "\n\n"
+	ollamaChunk := `{"response":"你好","done":false}` + "\n"
+
+	tests := []struct {
+		name     string
+		provider string
+		body     string
+		wantErr  error
+	}{
+		{"openai 完整响应", "openai", openaiChunk + "data: [DONE]\n\n", nil},
+		{"openai 缺少 [DONE]", "openai", openaiChunk, io.ErrUnexpectedEOF},
+		{"anthropic 完整响应", "anthropic", anthropicChunk + "event: message_stop\n" + `data: {"type":"message_stop"}` + "\n\n", nil},
+		{"anthropic 缺少 message_stop", "anthropic", anthropicChunk, io.ErrUnexpectedEOF},
+		{"ollama 完整响应", "ollama", ollamaChunk + `{"response":"","done":true,"eval_count":1}` + "\n", nil},
+		{"ollama 缺少 done", "ollama", ollamaChunk, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newTestServer(t, step{status: http.StatusOK, body: tt.body})
+			p := newTestProvider(t, tt.provider, srv.URL)
+
+			var text strings.Builder
+			_, err := p.Stream(context.Background(), Request{Messages: []Message{{Role: RoleUser, Content: "hi"}}},
+				func(delta string) error {
+					text.WriteString(delta)
+					return nil
+				})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Stream 错误 = %v，期望 %v", err, tt.wantErr)
+			}
+			if text.String() != "你好" {
+				t.Errorf("收到的内容 = %q，期望 %q", text.String(), "你好")
+			}
+		})
+	}
+}
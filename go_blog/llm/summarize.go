@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"go_blog/utils"
+	"strings"
+	"sync"
+)
+
+// 分块摘要并发数
+const mapConcurrency = 3
+
+// 分块摘要提示词
+const mapPrompt = "以下是一篇长文章的第 %d/%d 部分，请提取这一部分的要点，保留关键术语、代码和结论，不要补充原文没有的内容：\n\n%s"
+
+// 合并摘要时附在最终提示词后的说明
+const reduceIntro = "文章较长，以下是按顺序排列的各部分要点："
+
+// ProgressFunc 分块摘要进度回调
+type ProgressFunc func(done, total int)
+
+// Summarize 生成摘要并流式输出。文章未超过模型的分块大小时直接生成；
+// 否则按段落和标题切分，先并发总结每个分块，再将各部分要点合并为最终摘要
+func Summarize(ctx context.Context, p Provider, prompt string, blocks []utils.TextBlock, onProgress ProgressFunc, onDelta DeltaFunc) (Usage, error) {
+	chunkTokens, overlap := ChunkSettings(p.Model())
+	chunks := SplitBlocks(blocks, chunkTokens, overlap)
+
+	if len(chunks) <= 1 {
+		return p.Stream(ctx, Request{
+			Messages: []Message{{Role: RoleUser, Content: prompt + "\n\n" + strings.Join(chunks, "")}},
+		}, onDelta)
+	}
+
+	partials, usage, err := mapChunks(ctx, p, chunks, onProgress)
+	if err != nil {
+		return usage, err
+	}
+
+	var b strings.Builder
+	b.WriteString(prompt + "\n\n" + reduceIntro)
+	for i, partial := range partials {
+		fmt.Fprintf(&b, "\n\n### 第 %d 部分\n%s", i+1, partial)
+	}
+	final, err := p.Stream(ctx, Request{
+		Messages: []Message{{Role: RoleUser, Content: b.String()}},
+	}, onDelta)
+	usage.PromptTokens += final.PromptTokens
+	usage.CompletionTokens += final.CompletionTokens
+	return usage, err
+}
+
+// 并发总结每个分块，任一分块失败时取消其余请求
+func mapChunks(ctx context.Context, p Provider, chunks []string, onProgress ProgressFunc) ([]string, Usage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		usage    Usage
+		firstErr error
+		done     int
+	)
+	partials := make([]string, len(chunks))
+	sem := make(chan struct{}, mapConcurrency)
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			var b strings.Builder
+			u, err := p.Stream(ctx, Request{
+				Messages: []Message{{Role: RoleUser, Content: fmt.Sprintf(mapPrompt, i+1, len(chunks), chunk)}},
+			}, func(delta string) error {
+				b.WriteString(delta)
+				return nil
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			usage.PromptTokens += u.PromptTokens
+			usage.CompletionTokens += u.CompletionTokens
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			partials[i] = b.String()
+			done++
+			if onProgress != nil {
+				onProgress(done, len(chunks))
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return partials, usage, firstErr
+}
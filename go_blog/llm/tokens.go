@@ -0,0 +1,20 @@
+package llm
+
+import (
+	"unicode"
+)
+
+// EstimateTokens 粗略估算 token 数：中日韩字符按每字 1 个，其他字符按每 4 个 1 个
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case unicode.IsSpace(r):
+		default:
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
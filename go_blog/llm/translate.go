@@ -0,0 +1,381 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go_blog/utils"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrUnknownLanguage 请求了未配置的翻译语言
+var ErrUnknownLanguage = errors.New("不支持的翻译语言")
+
+// 默认配置
+const (
+	defaultTranslationBatchTokens = 1500
+)
+
+var defaultLanguages = []string{"zh", "en"}
+
+// 常用语言的显示名称，同时用于提示词
+var languageNames = map[string]string{
+	"zh":    "简体中文",
+	"zh-tw": "繁體中文",
+	"en":    "English",
+	"ja":    "日本語",
+	"ko":    "한국어",
+	"fr":    "Français",
+	"de":    "Deutsch",
+	"es":    "Español",
+	"ru":    "Русский",
+}
+
+// Language 可翻译的目标语言
+type Language struct {
+	Code string
+	Name string
+}
+
+// Languages 配置的目标语言列表
+func Languages() []Language {
+	codes := utils.AppConfig.AI.Translation.Languages
+	if len(codes) == 0 {
+		codes = defaultLanguages
+	}
+	languages := make([]Language, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToLower(code)
+		name, ok := languageNames[code]
+		if !ok {
+			name = code
+		}
+		languages = append(languages, Language{Code: code, Name: name})
+	}
+	return languages
+}
+
+// FindLanguage 查找配置的目标语言
+func FindLanguage(code string) (Language, error) {
+	code = strings.ToLower(code)
+	for _, l := range Languages() {
+		if l.Code == code {
+			return l, nil
+		}
+	}
+	return Language{}, ErrUnknownLanguage
+}
+
+const translatePrompt = `请将下面每个 <seg> 中的内容翻译为%s。要求：
+1. 保持 HTML 标签和属性原样不变，只翻译其中的文字；
+2. 形如 ⟦0⟧ 的占位符代表代码，必须原样保留在译文中的对应位置；
+3. 代码标识符、命令、产品名等专有名词保持原文；
+4. 按相同格式逐个输出 <seg id="编号">译文</seg>，不要合并或遗漏，不要输出其他内容。
+
+%s`
+
+var segPattern = regexp.MustCompile(`(?s)<seg id="(\d+)">(.*?)</seg>`)
+
+// 不翻译的元素，整体替换为占位符
+var keepElements = map[string]bool{
+	"pre": true, "code": true, "kbd": true, "samp": true, "var": true,
+	"script": true, "style": true, "svg": true, "math": true, "textarea": true,
+}
+
+// 块级元素，用于判断翻译单元的边界
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "details": true,
+	"dialog": true, "dd": true, "div": true, "dl": true, "dt": true, "fieldset": true,
+	"figcaption": true, "figure": true, "footer": true, "form": true, "header": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"section": true, "summary": true, "table": true, "thead": true, "tbody": true,
+	"tfoot": true, "tr": true, "td": true, "th": true, "caption": true, "ul": true,
+}
+
+// 一个翻译单元：父节点下连续的一段行内内容
+type segment struct {
+	parent *nethtml.Node
+	nodes  []*nethtml.Node
+	source string   // 待翻译的 HTML，代码已替换为占位符
+	keep   []string // 占位符对应的原始 HTML
+	result string
+}
+
+func placeholder(i int) string {
+	return "⟦" + strconv.Itoa(i) + "⟧"
+}
+
+// TranslateResult 翻译结果
+type TranslateResult struct {
+	Title   string
+	Summary string
+	Content string
+}
+
+// Translate 将文章的标题、摘要和 HTML 正文翻译为目标语言。
+// 正文按块级元素切分为翻译单元并分批翻译，代码块和行内代码不会发送给模型，
+// 译文缺失或占位符不完整的单元保留原文
+func Translate(ctx context.Context, p Provider, lang Language, title, summary, content string) (TranslateResult, Usage, error) {
+	body := &nethtml.Node{Type: nethtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := nethtml.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return TranslateResult{}, Usage{}, err
+	}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+
+	// 标题和摘要作为前两个单元一起翻译
+	segments := []*segment{
+		{source: html.EscapeString(title)},
+		{source: html.EscapeString(summary)},
+	}
+	collectSegments(body, &segments)
+
+	usage, err := translateSegments(ctx, p, lang, segments)
+	if err != nil {
+		return TranslateResult{}, usage, err
+	}
+
+	result := TranslateResult{
+		Title:   plainResult(segments[0], title),
+		Summary: plainResult(segments[1], summary),
+	}
+	for _, seg := range segments[2:] {
+		seg.apply()
+	}
+
+	var b bytes.Buffer
+	for n := body.FirstChild; n != nil; n = n.NextSibling {
+		if err := nethtml.Render(&b, n); err != nil {
+			return TranslateResult{}, usage, err
+		}
+	}
+	result.Content = b.String()
+	return result, usage, nil
+}
+
+// 标题、摘要等纯文本单元的译文
+func plainResult(seg *segment, original string) string {
+	if seg.result == "" {
+		return original
+	}
+	return strings.TrimSpace(html.UnescapeString(seg.result))
+}
+
+func hasBlockChild(n *nethtml.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && blockElements[c.Data] {
+			return true
+		}
+	}
+	return false
+}
+
+// 收集翻译单元：没有块级子元素的节点整体作为一个单元，否则递归处理块级子元素，
+// 块级子元素之间连续的行内内容各自作为一个单元
+func collectSegments(n *nethtml.Node, segments *[]*segment) {
+	var run []*nethtml.Node
+	flush := func() {
+		if seg := newSegment(n, run); seg != nil {
+			*segments = append(*segments, seg)
+		}
+		run = nil
+	}
+
+	if !hasBlockChild(n) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			run = append(run, c)
+		}
+		flush()
+		return
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == nethtml.ElementNode && blockElements[c.Data] {
+			flush()
+			if !keepElements[c.Data] {
+				collectSegments(c, segments)
+			}
+			continue
+		}
+		run = append(run, c)
+	}
+	flush()
+}
+
+// 创建翻译单元，没有需要翻译的文字时返回 nil
+func newSegment(parent *nethtml.Node, nodes []*nethtml.Node) *segment {
+	if len(nodes) == 0 || (parent.Type == nethtml.ElementNode && keepElements[parent.Data]) {
+		return nil
+	}
+
+	seg := &segment{parent: parent, nodes: nodes}
+	var b bytes.Buffer
+	for _, n := range nodes {
+		if err := nethtml.Render(&b, cloneForTranslation(n, &seg.keep)); err != nil {
+			return nil
+		}
+	}
+	seg.source = strings.TrimSpace(b.String())
+
+	// 只有占位符、空白或标点时无需翻译
+	text := seg.source
+	for i := range seg.keep {
+		text = strings.ReplaceAll(text, placeholder(i), "")
+	}
+	if strings.IndexFunc(html.UnescapeString(stripTags(text)), unicode.IsLetter) < 0 {
+		return nil
+	}
+	return seg
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+func stripTags(s string) string {
+	return tagPattern.ReplaceAllString(s, "")
+}
+
+// 复制节点用于生成待翻译的 HTML，不翻译的元素替换为占位符
+func cloneForTranslation(n *nethtml.Node, keep *[]string) *nethtml.Node {
+	if n.Type == nethtml.ElementNode && keepElements[n.Data] {
+		var b bytes.Buffer
+		nethtml.Render(&b, n)
+		*keep = append(*keep, b.String())
+		return &nethtml.Node{Type: nethtml.TextNode, Data: placeholder(len(*keep) - 1)}
+	}
+	c := &nethtml.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      append([]nethtml.Attribute(nil), n.Attr...),
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.AppendChild(cloneForTranslation(child, keep))
+	}
+	return c
+}
+
+// 用译文替换原节点，译文不完整时保留原文
+func (s *segment) apply() {
+	if s.result == "" {
+		return
+	}
+	translated := s.result
+	for i, original := range s.keep {
+		if !strings.Contains(translated, placeholder(i)) {
+			return
+		}
+		translated = strings.Replace(translated, placeholder(i), original, 1)
+	}
+
+	nodes, err := nethtml.ParseFragment(strings.NewReader(translated), s.parent)
+	if err != nil {
+		return
+	}
+	next := s.nodes[len(s.nodes)-1].NextSibling
+	for _, n := range s.nodes {
+		s.parent.RemoveChild(n)
+	}
+	for _, n := range nodes {
+		s.parent.InsertBefore(n, next)
+	}
+}
+
+// 将翻译单元按 token 数分批，每批一次请求，并发执行
+func translateSegments(ctx context.Context, p Provider, lang Language, segments []*segment) (Usage, error) {
+	batchTokens := utils.AppConfig.AI.Translation.BatchTokens
+	if batchTokens <= 0 {
+		batchTokens = defaultTranslationBatchTokens
+	}
+
+	var batches [][]int
+	var current []int
+	size := 0
+	for i, seg := range segments {
+		if seg.source == "" {
+			continue
+		}
+		tokens := EstimateTokens(seg.source)
+		if len(current) > 0 && size+tokens > batchTokens {
+			batches = append(batches, current)
+			current, size = nil, 0
+		}
+		current = append(current, i)
+		size += tokens
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		usage    Usage
+		firstErr error
+	)
+	sem := make(chan struct{}, mapConcurrency)
+
+	for _, batch := range batches {
+		wg.Add(1)
+		go func(batch []int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			var input strings.Builder
+			for _, i := range batch {
+				fmt.Fprintf(&input, "<seg id=\"%d\">%s</seg>\n", i, segments[i].source)
+			}
+			var output strings.Builder
+			u, err := p.Stream(ctx, Request{
+				Messages: []Message{{Role: RoleUser, Content: fmt.Sprintf(translatePrompt, lang.Name, input.String())}},
+			}, func(delta string) error {
+				output.WriteString(delta)
+				return nil
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			usage.PromptTokens += u.PromptTokens
+			usage.CompletionTokens += u.CompletionTokens
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			for _, m := range segPattern.FindAllStringSubmatch(output.String(), -1) {
+				i, _ := strconv.Atoi(m[1])
+				if i < len(segments) {
+					segments[i].result = strings.TrimSpace(m[2])
+				}
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return usage, firstErr
+}
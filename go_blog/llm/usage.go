@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"context"
+	"go_blog/apperr"
+	"go_blog/models"
+	"go_blog/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBudgetExceeded 本月 AI 费用已达到预算上限
+var ErrBudgetExceeded = apperr.New(apperr.Unavailable, "budget_exceeded", "本月 AI 预算已用完")
+
+// metered 记录每次调用用量的服务商包装
+type metered struct {
+	Provider
+	feature string
+	postID  uint
+}
+
+// WithUsage 包装服务商，每次调用后记录用量、费用和耗时
+func WithUsage(p Provider, feature string, postID uint) Provider {
+	return &metered{Provider: p, feature: feature, postID: postID}
+}
+
+// Stream 调用服务商并记录用量，服务商未返回用量时在本地估算
+func (m *metered) Stream(ctx context.Context, req Request, onDelta DeltaFunc) (Usage, error) {
+	promptTokens := EstimateTokens(req.System)
+	for _, msg := range req.Messages {
+		promptTokens += EstimateTokens(msg.Content)
+	}
+	release, err := reserveBudget(Cost(m.Model(), Usage{PromptTokens: promptTokens, CompletionTokens: reserveCompletionTokens}))
+	if err != nil {
+		return Usage{}, err
+	}
+	defer release()
+
+	start := time.Now()
+	var output strings.Builder
+	usage, err := m.Provider.Stream(ctx, req, func(delta string) error {
+		output.WriteString(delta)
+		return onDelta(delta)
+	})
+
+	estimated := false
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = promptTokens
+		estimated = true
+	}
+	if usage.CompletionTokens == 0 && output.Len() > 0 {
+		usage.CompletionTokens = EstimateTokens(output.String())
+		estimated = true
+	}
+
+	recordUsage(m.feature, m.postID, m.Name(), m.Model(), usage, estimated, start, err)
+	return usage, err
+}
+
+// 记录一次调用的用量、费用和耗时，同时更新监控指标
+func recordUsage(feature string, postID uint, provider, model string, usage Usage, estimated bool, start time.Time, err error) {
+	record := &models.AIUsage{
+		CreatedAt:        start,
+		Feature:          feature,
+		PostID:           postID,
+		Provider:         provider,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Estimated:        estimated,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		Success:          err == nil,
+		Cost:             Cost(model, usage),
+	}
+	metrics.observe(record)
+	if recordErr := models.RecordUsage(record); recordErr != nil {
+		utils.Log.WithError(recordErr).WithFields(logrus.Fields{
+			"feature": feature,
+			"post_id": postID,
+		}).Error("记录 AI 用量失败")
+	}
+}
+
+// Cost 按模型配置的单价计算费用
+func Cost(model string, usage Usage) float64 {
+	cfg := modelConfig(model)
+	return (float64(usage.PromptTokens)*cfg.PromptPrice + float64(usage.CompletionTokens)*cfg.CompletionPrice) / 1e6
+}
+
+// MonthStart 本月第一天零点
+func MonthStart() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// 预留费用时按这么多输出 token 估算，实际输出更长时以记录的用量为准
+const reserveCompletionTokens = 1024
+
+// 已预留但尚未记录用量的费用。检查和预留在同一把锁内完成，
+// 并发请求不会同时通过检查；超出预算的部分不超过进行中调用的实际费用与预估费用之差
+var budget struct {
+	sync.Mutex
+	reserved float64
+}
+
+// CheckBudget 检查本月费用（含进行中调用的预留费用）是否已超出预算，
+// 用于在开始输出前提前返回错误，每次调用服务商前还会再预留费用
+func CheckBudget() error {
+	limit := utils.AppConfig.AI.MonthlyBudget
+	if limit <= 0 {
+		return nil
+	}
+	budget.Lock()
+	defer budget.Unlock()
+	cost, err := models.GetCostSince(MonthStart())
+	if err != nil {
+		return err
+	}
+	if cost+budget.reserved >= limit {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// 检查预算并预留一次调用的预估费用，预留后超出预算时返回 ErrBudgetExceeded。
+// 返回的函数需在记录用量后调用以释放预留
+func reserveBudget(estimate float64) (func(), error) {
+	limit := utils.AppConfig.AI.MonthlyBudget
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	budget.Lock()
+	defer budget.Unlock()
+	cost, err := models.GetCostSince(MonthStart())
+	if err != nil {
+		return nil, err
+	}
+	if cost+budget.reserved >= limit || cost+budget.reserved+estimate > limit {
+		return nil, ErrBudgetExceeded
+	}
+	budget.reserved += estimate
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			budget.Lock()
+			budget.reserved -= estimate
+			budget.Unlock()
+		})
+	}, nil
+}
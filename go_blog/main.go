@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"go_blog/embedding"
+	"go_blog/llm"
 	"go_blog/models"
 	"go_blog/routes"
+	"go_blog/search"
+	"go_blog/transform"
 	"go_blog/utils"
 	"log"
+	"time"
 )
 
 func main() {
@@ -19,9 +24,47 @@ func main() {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
 
+	// 初始化 AI 服务商
+	if err := llm.Init(); err != nil {
+		log.Fatalf("AI 服务商初始化失败: %v", err)
+	}
+
+	// 初始化正文处理流程，需在启动读取正文的后台任务之前完成
+	if err := transform.Init(); err != nil {
+		log.Fatalf("正文处理流程初始化失败: %v", err)
+	}
+
 	// 设置路由
 	r := routes.SetupRouter()
 
+	// 在后台补全文章字数和阅读时间，爬虫等外部程序会直接写数据库，定时补全以保持同步
+	go func() {
+		if err := models.BackfillReadingStats(); err != nil {
+			utils.Log.WithError(err).Error("补全文章阅读时间失败")
+		}
+		interval := models.ReadingBackfillInterval()
+		if interval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := models.BackfillReadingStats(); err != nil {
+				utils.Log.WithError(err).Error("补全文章阅读时间失败")
+			}
+		}
+	}()
+
+	// 初始化搜索索引
+	if err := search.Init(); err != nil {
+		log.Fatalf("搜索索引初始化失败: %v", err)
+	}
+
+	// 加载文章向量并在后台补全
+	if err := embedding.Init(); err != nil {
+		log.Fatalf("文章向量初始化失败: %v", err)
+	}
+
 	// 启动服务器
 	serverAddr := fmt.Sprintf("%s:%s",
 		utils.AppConfig.Server.Host,
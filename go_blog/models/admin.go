@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"go_blog/apperr"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuditLog 后台操作审计日志
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey;comment:日志ID"`
+	CreatedAt time.Time `gorm:"index;comment:操作时间"`
+	Username  string    `gorm:"size:50;index;comment:操作人"`
+	Action    string    `gorm:"size:20;comment:操作类型"`
+	PostID    uint      `gorm:"index;comment:文章ID"`
+	Detail    string    `gorm:"size:500;comment:操作详情"`
+	ClientIP  string    `gorm:"size:64;comment:客户端IP"`
+}
+
+// AdminState 后台全局状态，只有一行
+type AdminState struct {
+	ID           uint  `gorm:"primarykey;comment:ID"`
+	SessionEpoch int64 `gorm:"default:0;comment:会话版本，退出登录时递增使已签发的会话失效"`
+}
+
+// 后台状态所在行的ID
+const adminStateID = 1
+
+// GetSessionEpoch 获取当前的会话版本
+func GetSessionEpoch() (int64, error) {
+	state := AdminState{ID: adminStateID}
+	if err := DB.FirstOrCreate(&state).Error; err != nil {
+		return 0, err
+	}
+	return state.SessionEpoch, nil
+}
+
+// BumpSessionEpoch 递增会话版本，之前签发的所有后台会话随之失效
+func BumpSessionEpoch() error {
+	if _, err := GetSessionEpoch(); err != nil {
+		return err
+	}
+	return DB.Model(&AdminState{ID: adminStateID}).
+		UpdateColumn("session_epoch", gorm.Expr("session_epoch + 1")).Error
+}
+
+// ErrInvalidPost 文章字段校验失败
+var ErrInvalidPost = apperr.NewInvalid("invalid_post", "文章标题和发布时间不能为空")
+
+// AdminGetPosts 后台获取文章列表，包含未发布的文章
+func AdminGetPosts(page int, pageSize int) ([]Post, int64, error) {
+	var posts []Post
+	var total int64
+
+	if err := DB.Model(&Post{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := DB.Select("id, title, summary, category, publish_time, image_url, status, created_at, updated_at, deleted_at").
+		Order("publish_time desc, id desc").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&posts).Error
+
+	return posts, total, err
+}
+
+// AdminGetPost 后台获取文章原始内容，不做任何转换
+func AdminGetPost(id int) (*Post, error) {
+	var post Post
+	if err := DB.Scopes(preloadTags).First(&post, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPostNotFound
+		}
+		return nil, err
+	}
+	return &post, nil
+}
+
+// CreatePost 创建文章，同时保存文章标签
+func CreatePost(post *Post) error {
+	if post.Title == "" || post.PublishTime.IsZero() {
+		return ErrInvalidPost
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(post).Error; err != nil {
+			return err
+		}
+		return saveTags(tx, post)
+	})
+}
+
+// UpdatePost 更新文章的可编辑字段和标签
+func UpdatePost(post *Post) error {
+	if post.Title == "" || post.PublishTime.IsZero() {
+		return ErrInvalidPost
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(post).Select("Title", "Summary", "Category", "PublishTime", "ImageUrl", "Content", "ContentFormat", "HTMLContent",
+			"CharCount", "WordCount", "ReadingTime", "ReadingCounted").Updates(post).Error
+		if err != nil {
+			return err
+		}
+		return saveTags(tx, post)
+	})
+}
+
+// SetPostStatus 修改文章发布状态
+func SetPostStatus(id int, status string) error {
+	post, err := AdminGetPost(id)
+	if err != nil {
+		return err
+	}
+	return DB.Model(post).Omit(clause.Associations).Update("status", status).Error
+}
+
+// DeletePost 删除文章（软删除）
+func DeletePost(id int) error {
+	result := DB.Delete(&Post{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// CreateAuditLog 写入审计日志
+func CreateAuditLog(log *AuditLog) error {
+	return DB.Create(log).Error
+}
+
+// GetAuditLogs 获取最近的审计日志
+func GetAuditLogs(limit int) ([]AuditLog, error) {
+	var logs []AuditLog
+	err := DB.Order("id desc").Limit(limit).Find(&logs).Error
+	return logs, err
+}
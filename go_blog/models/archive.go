@@ -0,0 +1,40 @@
+package models
+
+// ArchiveMonth 某个月份的已发布文章数
+type ArchiveMonth struct {
+	Year  int   `json:"year"`
+	Month int   `json:"month"`
+	Count int64 `json:"count"`
+}
+
+// ArchiveYear 某一年的已发布文章数，按月份倒序列出
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	Count  int64          `json:"count"`
+	Months []ArchiveMonth `json:"months"`
+}
+
+// GetArchive 按发布时间的年、月统计已发布文章数，按时间倒序
+func GetArchive() ([]ArchiveYear, error) {
+	var months []ArchiveMonth
+	err := DB.Model(&Post{}).
+		Scopes(published).
+		Select("YEAR(publish_time) AS year, MONTH(publish_time) AS month, COUNT(*) AS count").
+		Group("year, month").
+		Order("year DESC, month DESC").
+		Scan(&months).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var years []ArchiveYear
+	for _, m := range months {
+		if len(years) == 0 || years[len(years)-1].Year != m.Year {
+			years = append(years, ArchiveYear{Year: m.Year})
+		}
+		y := &years[len(years)-1]
+		y.Count += m.Count
+		y.Months = append(y.Months, m)
+	}
+	return years, nil
+}
@@ -18,5 +18,5 @@ func InitDB() error {
 	DB = db
 
 	// 自动迁移
-	return DB.AutoMigrate(&Post{})
+	return DB.AutoMigrate(&Post{}, &AuditLog{}, &SearchDocument{}, &PostSummary{}, &AIUsage{}, &AIUsageDaily{}, &PostTranslation{}, &PostEmbedding{}, &Tag{}, &AdminState{})
 }
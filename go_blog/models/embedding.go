@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// PostEmbedding 文章向量，按模型和文本哈希判断是否需要重新计算
+type PostEmbedding struct {
+	PostID     uint      `gorm:"primarykey;autoIncrement:false;comment:文章ID"`
+	Model      string    `gorm:"size:100;comment:向量模型"`
+	SourceHash string    `gorm:"size:64;comment:文本哈希"`
+	Dimensions int       `gorm:"comment:向量维度"`
+	Vector     []byte    `gorm:"type:mediumblob;comment:向量（float32 小端序）"`
+	UpdatedAt  time.Time `gorm:"comment:计算时间"`
+}
+
+// EncodeVector 将向量编码为 float32 小端序字节
+func EncodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+// DecodeVector 解码 EncodeVector 编码的向量
+func DecodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
+
+// SaveEmbedding 新增或更新文章向量
+func SaveEmbedding(e *PostEmbedding) error {
+	return DB.Save(e).Error
+}
+
+// GetEmbeddings 获取指定模型下所有已发布文章的向量
+func GetEmbeddings(model string) ([]PostEmbedding, error) {
+	var embeddings []PostEmbedding
+	err := DB.Table("post_embeddings").
+		Joins("JOIN posts ON posts.id = post_embeddings.post_id AND posts.deleted_at IS NULL AND posts.status = ?", PostStatusPublished).
+		Where("post_embeddings.model = ?", model).
+		Select("post_embeddings.*").
+		Scan(&embeddings).Error
+	return embeddings, err
+}
+
+// GetEmbeddingVersions 获取已计算向量的模型和文本哈希，用于判断哪些文章需要重新计算
+func GetEmbeddingVersions() (map[uint]PostEmbedding, error) {
+	var rows []PostEmbedding
+	if err := DB.Select("post_id, model, source_hash").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	versions := make(map[uint]PostEmbedding, len(rows))
+	for _, r := range rows {
+		versions[r.PostID] = r
+	}
+	return versions, nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"go_blog/utils"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	// 测试中不写日志文件
+	utils.Log = logrus.New()
+	utils.Log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// 使用内存中的 SQLite 数据库，每个测试独立
+func openTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&Post{}, &Tag{}, &PostTranslation{}); err != nil {
+		t.Fatal(err)
+	}
+	old := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = old
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// 创建一篇已发布的文章
+func createTestPost(t *testing.T, post *Post) *Post {
+	t.Helper()
+	if post.PublishTime.IsZero() {
+		post.PublishTime = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	}
+	if post.Status == "" {
+		post.Status = PostStatusPublished
+	}
+	if err := CreatePost(post); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
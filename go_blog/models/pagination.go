@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go_blog/apperr"
+	"time"
+)
+
+// 文章列表的排序方式
+const (
+	SortNewest  = "newest"  // 发布时间倒序
+	SortOldest  = "oldest"  // 发布时间正序
+	SortTitle   = "title"   // 标题
+	SortUpdated = "updated" // 最近更新
+)
+
+// ErrInvalidCursor 游标格式错误或与排序方式不一致
+var ErrInvalidCursor = apperr.NewInvalid("invalid_cursor", "无效的分页游标")
+
+// 排序字段和方向，相同时再按 ID 排序保证顺序稳定
+type sortOrder struct {
+	name   string
+	column string
+	desc   bool
+}
+
+var sortOrders = map[string]sortOrder{
+	SortNewest:  {name: SortNewest, column: "publish_time", desc: true},
+	SortOldest:  {name: SortOldest, column: "publish_time"},
+	SortTitle:   {name: SortTitle, column: "title"},
+	SortUpdated: {name: SortUpdated, column: "updated_at", desc: true},
+}
+
+// ValidSort 判断排序方式是否支持，空字符串表示默认排序
+func ValidSort(sort string) bool {
+	_, ok := sortOrders[sort]
+	return ok || sort == ""
+}
+
+// 未知的排序方式按发布时间倒序
+func sortOrderFor(sort string) sortOrder {
+	if o, ok := sortOrders[sort]; ok {
+		return o
+	}
+	return sortOrders[SortNewest]
+}
+
+func (o sortOrder) clause() string {
+	dir := "ASC"
+	if o.desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", o.column, dir, dir)
+}
+
+// Cursor 游标分页的位置，记录上一页最后一篇文章的排序字段和 ID
+type Cursor struct {
+	Sort  string    `json:"s"`
+	Time  time.Time `json:"t,omitempty"`
+	Title string    `json:"v,omitempty"`
+	ID    uint      `json:"id"`
+}
+
+// NewCursor 生成指向文章 p 之后的游标
+func NewCursor(sort string, p *Post) *Cursor {
+	order := sortOrderFor(sort)
+	c := &Cursor{Sort: order.name, ID: p.ID}
+	switch order.column {
+	case "title":
+		c.Title = p.Title
+	case "updated_at":
+		c.Time = p.UpdatedAt
+	default:
+		c.Time = p.PublishTime
+	}
+	return c
+}
+
+// 游标中与排序字段比较的值
+func (c *Cursor) value() interface{} {
+	if c.Sort == SortTitle {
+		return c.Title
+	}
+	return c.Time
+}
+
+// Encode 将游标编码为 URL 安全的字符串
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析 Encode 生成的游标
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	if _, ok := sortOrders[c.Sort]; !ok {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
@@ -1,8 +1,13 @@
 package models
 
 import (
+	"container/list"
+	"errors"
+	"go_blog/apperr"
+	"go_blog/transform"
+	"html"
 	"html/template"
-	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,32 +15,140 @@ import (
 
 type Post struct {
 	gorm.Model
-	ID          uint          `gorm:"primarykey;comment:文章ID"`
-	Title       string        `gorm:"size:200;not null;comment:文章标题"`
-	Summary     string        `gorm:"size:500;comment:文章摘要"`
-	Content     string        `gorm:"type:longtext;comment:文章内容"`
-	HTMLContent template.HTML `gorm:"-"`
-	Category    string        `gorm:"size:20;index;comment:文章分类"`
-	PublishTime time.Time     `gorm:"type:date;not null;comment:发布时间"`
-	ImageUrl    string        `gorm:"size:255;comment:文章配图URL"`
-}
-
-// 获取文章列表
-func GetPosts(page int, pageSize int, category string) ([]Post, int64, error) {
-	var posts []Post
-	var total int64
+	ID             uint                  `gorm:"primarykey;comment:文章ID"`
+	Title          string                `gorm:"size:200;not null;comment:文章标题"`
+	Summary        string                `gorm:"size:500;comment:文章摘要"`
+	Content        string                `gorm:"type:longtext;comment:文章内容"`
+	ContentFormat  string                `gorm:"size:10;default:html;comment:内容格式"`
+	HTMLContent    template.HTML         `gorm:"type:longtext;comment:Markdown渲染缓存"`
+	DisplayHTML    template.HTML         `gorm:"-"` // 经过正文处理流程、用于展示的 HTML
+	Category       string                `gorm:"size:20;index;comment:文章分类"`
+	PublishTime    time.Time             `gorm:"type:date;not null;index;comment:发布时间"`
+	ImageUrl       string                `gorm:"size:255;comment:文章配图URL"`
+	Status         string                `gorm:"size:20;default:published;index;comment:文章状态"`
+	Source         string                `gorm:"size:255;comment:来源站点"`
+	CharCount      int                   `gorm:"default:0;comment:中日韩文字数"`
+	WordCount      int                   `gorm:"default:0;comment:英文等单词数"`
+	ReadingTime    int                   `gorm:"default:0;comment:预计阅读时间（分钟）"`
+	ReadingCounted bool                  `gorm:"default:false;index;comment:是否已统计字数"`
+	Tags           []Tag                 `gorm:"many2many:post_tags;"`
+	TOC            []*transform.TOCEntry `gorm:"-"`
+}
 
-	query := DB
-	if category != "" {
-		query = query.Where("category = ?", category)
+// 文章内容格式
+const (
+	ContentFormatHTML     = "html"
+	ContentFormatMarkdown = "markdown"
+)
+
+// ErrInvalidContentFormat 不支持的内容格式
+var ErrInvalidContentFormat = apperr.NewInvalid("invalid_post", "内容格式只能是 html 或 markdown")
+
+// ErrPostNotFound 文章不存在或未发布，仍可用 gorm.ErrRecordNotFound 判断
+var ErrPostNotFound = &apperr.Error{
+	Kind:    apperr.NotFound,
+	Code:    "not_found",
+	Message: "文章不存在",
+	Err:     gorm.ErrRecordNotFound,
+}
+
+// BeforeSave 保存前校验内容格式，Markdown 文章渲染为 HTML 缓存在 HTMLContent 中，并重新统计字数
+func (p *Post) BeforeSave(tx *gorm.DB) error {
+	if p.ContentFormat == "" {
+		p.ContentFormat = ContentFormatHTML
+	}
+	switch p.ContentFormat {
+	case ContentFormatHTML:
+		p.HTMLContent = ""
+	case ContentFormatMarkdown:
+		rendered, err := transform.RenderMarkdown(p.Content)
+		if err != nil {
+			return err
+		}
+		p.HTMLContent = template.HTML(rendered)
+	default:
+		return ErrInvalidContentFormat
+	}
+	p.updateReadingStats()
+	return nil
+}
+
+// SourceHTML 返回未经处理的正文 HTML，Markdown 文章优先使用渲染缓存
+func (p *Post) SourceHTML() string {
+	if p.ContentFormat != ContentFormatMarkdown {
+		return p.Content
+	}
+	if p.HTMLContent != "" {
+		return string(p.HTMLContent)
+	}
+	// 直接写入数据库的文章没有渲染缓存，临时渲染
+	rendered, err := transform.RenderMarkdown(p.Content)
+	if err != nil {
+		return html.EscapeString(p.Content)
+	}
+	return rendered
+}
+
+// 文章状态
+const (
+	PostStatusPublished = "published"
+	PostStatusDraft     = "draft"
+)
+
+// 只查询已发布的文章
+func published(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", PostStatusPublished)
+}
+
+// PostFilter 文章列表的筛选条件
+type PostFilter struct {
+	Category string
+	// Tags 只返回同时带有这些标签的文章
+	Tags []string
+	// PublishedFrom、PublishedBefore 发布时间范围 [From, Before)，零值表示不限制
+	PublishedFrom   time.Time
+	PublishedBefore time.Time
+}
+
+// 标签按名称排序预加载
+func preloadTags(db *gorm.DB) *gorm.DB {
+	return db.Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("tags.name")
+	})
+}
+
+// 列表页查询的字段（不含正文）
+const listColumns = "id, title, summary, category, publish_time, image_url, char_count, word_count, reading_time, created_at, updated_at, deleted_at"
+
+// 按筛选条件查询已发布的文章
+func filterPosts(filter PostFilter) *gorm.DB {
+	query := DB.Model(&Post{}).Scopes(published, withTags(filter.Tags))
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
 	}
+	if !filter.PublishedFrom.IsZero() {
+		query = query.Where("publish_time >= ?", filter.PublishedFrom)
+	}
+	if !filter.PublishedBefore.IsZero() {
+		query = query.Where("publish_time < ?", filter.PublishedBefore)
+	}
+	return query
+}
+
+// 获取文章列表，sort 为空时按发布时间倒序
+func GetPosts(page int, pageSize int, filter PostFilter, sort string) ([]Post, int64, error) {
+	var posts []Post
+	var total int64
 
 	// 获取总数
-	query.Model(&Post{}).Count(&total)
+	if err := filterPosts(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
 	// 获取分页数据
-	err := query.Select("id, title, summary, category, publish_time, image_url, created_at, updated_at, deleted_at").
-		Order("publish_time desc").
+	err := filterPosts(filter).Scopes(preloadTags).
+		Select(listColumns).
+		Order(sortOrderFor(sort).clause()).
 		Offset((page - 1) * pageSize).
 		Limit(pageSize).
 		Find(&posts).Error
@@ -43,25 +156,198 @@ func GetPosts(page int, pageSize int, category string) ([]Post, int64, error) {
 	return posts, total, err
 }
 
+// GetPostsAfter 游标分页获取文章列表，cursor 为 nil 时从第一篇开始。
+// 返回的游标指向本页最后一篇文章，没有更多文章时为 nil
+func GetPostsAfter(cursor *Cursor, limit int, filter PostFilter, sort string) ([]Post, *Cursor, error) {
+	order := sortOrderFor(sort)
+	query := filterPosts(filter)
+	if cursor != nil {
+		if cursor.Sort != order.name {
+			return nil, nil, ErrInvalidCursor
+		}
+		op := ">"
+		if order.desc {
+			op = "<"
+		}
+		value := cursor.value()
+		query = query.Where("("+order.column+" "+op+" ? OR ("+order.column+" = ? AND id "+op+" ?))",
+			value, value, cursor.ID)
+	}
+
+	// 多取一篇判断是否还有下一页
+	var posts []Post
+	err := query.Scopes(preloadTags).
+		Select(listColumns).
+		Order(order.clause()).
+		Limit(limit + 1).
+		Find(&posts).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(posts) <= limit {
+		return posts, nil, nil
+	}
+	posts = posts[:limit]
+	return posts, NewCursor(order.name, &posts[limit-1]), nil
+}
+
 // GetCategories 获取所有分类
 func GetCategories() ([]string, error) {
 	var categories []string
 	err := DB.Model(&Post{}).
+		Scopes(published).
 		Distinct().
 		Pluck("category", &categories).
 		Error
 	return categories, err
 }
 
+// GetPostByID 获取已发布的文章详情
 func GetPostByID(id int) (*Post, error) {
 	var post Post
-	result := DB.First(&post, id)
+	result := DB.Scopes(published, preloadTags).First(&post, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, ErrPostNotFound
+	}
 	if result.Error != nil {
 		return nil, result.Error
 	}
-	// 替换HTML内容中的src="/为src="https://www.30secondsofcode.org/
-	content := strings.ReplaceAll(post.Content, `/assets/cover/`, `https://www.30secondsofcode.org/assets/cover/`)
-	content = strings.ReplaceAll(content, `href="/`, `href="https://www.30secondsofcode.org/`)
-	post.HTMLContent = template.HTML(content)
+	// 定时补全之前写入的文章只在内存中统计，由 BackfillReadingStats 保存
+	if !post.ReadingCounted {
+		post.updateReadingStats()
+	}
+	post.renderContent()
 	return &post, nil
 }
+
+// 展示内容缓存的最大条目数，超过后淘汰最久未使用的条目
+const renderCacheSize = 512
+
+// 展示内容缓存，按处理流程版本和文章版本（来源站点和正文的哈希）区分，代码高亮等步骤较慢
+var renderCache = &renderLRU{
+	items: make(map[string]*list.Element),
+	order: list.New(),
+}
+
+type renderedContent struct {
+	html template.HTML
+	toc  []*transform.TOCEntry
+}
+
+// 按最近使用顺序淘汰的展示内容缓存
+type renderLRU struct {
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List // 队首为最近使用的条目
+}
+
+type renderEntry struct {
+	key   string
+	value renderedContent
+}
+
+func (c *renderLRU) Get(key string) (renderedContent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return renderedContent{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*renderEntry).value, true
+}
+
+func (c *renderLRU) Add(key string, value renderedContent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*renderEntry).value = value
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&renderEntry{key: key, value: value})
+	for c.order.Len() > renderCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*renderEntry).key)
+	}
+}
+
+// 生成用于展示的HTML内容和目录，写入 DisplayHTML：Markdown 文章先取渲染结果，再按配置依次改写地址、过滤不安全的标签、高亮代码、生成标题锚点等。
+// HTMLContent 始终保持为 Markdown 的原始渲染结果，供 SourceHTML 提取纯文本和翻译使用
+func (p *Post) renderContent() {
+	pipeline := transform.Default()
+	source := p.SourceHTML()
+	key := HashText(pipeline.Version() + "\x00" + p.Source + "\x00" + source)
+
+	cached, ok := renderCache.Get(key)
+	if !ok {
+		rendered := pipeline.Apply(source, p.Source)
+		cached = renderedContent{html: template.HTML(rendered), toc: transform.TOC(rendered)}
+		renderCache.Add(key, cached)
+	}
+	p.DisplayHTML = cached.html
+	p.TOC = cached.toc
+}
+
+// GetFeedPosts 获取订阅源使用的最新文章，包含处理后的正文
+func GetFeedPosts(category string, limit int) ([]Post, error) {
+	var posts []Post
+
+	query := DB.Scopes(published)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	err := query.Scopes(preloadTags).Order("publish_time desc, id desc").
+		Limit(limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range posts {
+		posts[i].renderContent()
+	}
+	return posts, nil
+}
+
+// GetPostsByIDs 按给定的ID顺序获取已发布的文章列表（不含正文）
+func GetPostsByIDs(ids []uint) ([]Post, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var posts []Post
+	err := DB.Scopes(published, preloadTags).
+		Select(listColumns).
+		Where("id IN ?", ids).
+		Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID] = p
+	}
+	ordered := make([]Post, 0, len(posts))
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered, nil
+}
+
+// EachPublishedPost 分批遍历所有已发布的文章
+func EachPublishedPost(fn func(*Post) error) error {
+	var batch []Post
+	return DB.Scopes(published).FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"go_blog/transform"
+	"strings"
+	"testing"
+)
+
+func TestGetPostByIDKeepsMarkdownSource(t *testing.T) {
+	openTestDB(t)
+	post := createTestPost(t, &Post{
+		Title:         "Markdown",
+		ContentFormat: ContentFormatMarkdown,
+		Content:       "## Hello\n\n正文 [链接](https://example.com)\n\n```go\nfmt.Println(1)\n```\n",
+	})
+	want, err := transform.RenderMarkdown(post.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 多次读取后渲染缓存和 SourceHTML 仍是 Markdown 的原始渲染结果
+	var loaded *Post
+	for i := 0; i < 2; i++ {
+		loaded, err = GetPostByID(int(post.ID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(loaded.HTMLContent) != want || loaded.SourceHTML() != want {
+			t.Fatalf("第 %d 次读取后 SourceHTML = %q，期望 %q", i+1, loaded.SourceHTML(), want)
+		}
+		if n := strings.Count(string(loaded.DisplayHTML), "heading-anchor"); n != 1 {
+			t.Fatalf("第 %d 次读取后锚点数 = %d，期望 1", i+1, n)
+		}
+	}
+
+	// 翻译的输入是原始 HTML，译文经过处理后每个标题只有一个锚点
+	source := loaded.SourceHTML()
+	if strings.Contains(source, "heading-anchor") {
+		t.Fatalf("翻译输入包含展示用的锚点: %q", source)
+	}
+	loaded.ApplyTranslation(&PostTranslation{Title: "Translated", Content: source})
+	if n := strings.Count(string(loaded.DisplayHTML), "heading-anchor"); n != 1 {
+		t.Errorf("译文锚点数 = %d，期望 1: %s", n, loaded.DisplayHTML)
+	}
+
+	// 数据库中的渲染缓存没有被展示内容覆盖
+	var stored Post
+	if err := DB.First(&stored, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if string(stored.HTMLContent) != want {
+		t.Errorf("数据库中的 HTMLContent = %q，期望 %q", stored.HTMLContent, want)
+	}
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"go_blog/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 默认阅读速度
+const (
+	defaultCJKPerMinute   = 300
+	defaultWordsPerMinute = 200
+)
+
+// 默认定时补全字数和阅读时间的间隔（分钟）
+const defaultReadingBackfillInterval = 30
+
+// ReadingBackfillInterval 定时补全字数和阅读时间的间隔，为 0 时只在启动时补全
+func ReadingBackfillInterval() time.Duration {
+	interval := utils.AppConfig.Content.Reading.BackfillInterval
+	if interval < 0 {
+		return 0
+	}
+	if interval == 0 {
+		interval = defaultReadingBackfillInterval
+	}
+	return time.Duration(interval) * time.Minute
+}
+
+// Words 文章总字数，汉字按字、英文等按单词计算
+func (p *Post) Words() int {
+	return p.CharCount + p.WordCount
+}
+
+// 根据正文统计字数并估算阅读时间，正文变化时在保存前调用
+func (p *Post) updateReadingStats() {
+	p.CharCount, p.WordCount = utils.CountWords(utils.ExtractText(p.SourceHTML()))
+	p.ReadingTime = readingMinutes(p.CharCount, p.WordCount)
+	p.ReadingCounted = true
+}
+
+// 没有统计过的文章（如爬虫直接写入的文章）统计字数并保存，正文为空的文章同样标记为已统计，避免重复处理
+func (p *Post) fillReadingStats() error {
+	if p.ReadingCounted {
+		return nil
+	}
+	p.updateReadingStats()
+	return DB.Model(p).UpdateColumns(map[string]interface{}{
+		"char_count":      p.CharCount,
+		"word_count":      p.WordCount,
+		"reading_time":    p.ReadingTime,
+		"reading_counted": true,
+	}).Error
+}
+
+// 按配置的阅读速度估算阅读时间（分钟），有内容时至少为 1 分钟
+func readingMinutes(chars, words int) int {
+	cfg := utils.AppConfig.Content.Reading
+	cjkSpeed := cfg.CJKPerMinute
+	if cjkSpeed <= 0 {
+		cjkSpeed = defaultCJKPerMinute
+	}
+	wordSpeed := cfg.WordsPerMinute
+	if wordSpeed <= 0 {
+		wordSpeed = defaultWordsPerMinute
+	}
+
+	if chars == 0 && words == 0 {
+		return 0
+	}
+	seconds := chars*60/cjkSpeed + words*60/wordSpeed
+	minutes := (seconds + 59) / 60
+	if minutes < 1 {
+		minutes = 1
+	}
+	return minutes
+}
+
+// BackfillReadingStats 补全没有统计过的文章（如爬虫直接写入的文章），
+// 并在阅读速度配置变化后按已保存的字数重新计算阅读时间。启动时和之后定时执行
+func BackfillReadingStats() error {
+	var batch []Post
+	err := DB.Where("reading_counted = ?", false).
+		FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				if err := batch[i].fillReadingStats(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	var stats []Post
+	err = DB.Select("id, char_count, word_count, reading_time").
+		Where("char_count > 0 OR word_count > 0").
+		FindInBatches(&stats, 1000, func(tx *gorm.DB, _ int) error {
+			for _, p := range stats {
+				minutes := readingMinutes(p.CharCount, p.WordCount)
+				if minutes == p.ReadingTime {
+					continue
+				}
+				if err := DB.Model(&Post{}).Where("id = ?", p.ID).UpdateColumn("reading_time", minutes).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	return err
+}
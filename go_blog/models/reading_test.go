@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 模拟爬虫直接写入、没有统计过字数的文章
+func insertUncountedPost(t *testing.T, content string) *Post {
+	t.Helper()
+	post := &Post{
+		Title:         "爬虫文章",
+		Content:       content,
+		ContentFormat: ContentFormatHTML,
+		Status:        PostStatusPublished,
+		PublishTime:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+	if err := DB.Session(&gorm.Session{SkipHooks: true}).Create(post).Error; err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func countUncounted(t *testing.T) int64 {
+	t.Helper()
+	var n int64
+	if err := DB.Model(&Post{}).Where("reading_counted = ?", false).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestBackfillReadingStatsMarksEmptyPosts(t *testing.T) {
+	openTestDB(t)
+	text := insertUncountedPost(t, "<p>Hello world 你好</p>")
+	insertUncountedPost(t, `<p><img src="/a.png"></p>`)
+	if n := countUncounted(t); n != 2 {
+		t.Fatalf("未统计的文章数 = %d，期望 2", n)
+	}
+
+	if err := BackfillReadingStats(); err != nil {
+		t.Fatal(err)
+	}
+	// 只有图片的文章也标记为已统计，之后不再重复处理
+	if n := countUncounted(t); n != 0 {
+		t.Errorf("补全后仍有 %d 篇文章未统计", n)
+	}
+	var stored Post
+	if err := DB.First(&stored, text.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.CharCount != 2 || stored.WordCount != 2 || stored.ReadingTime != 1 {
+		t.Errorf("统计结果 = %d 字、%d 词、%d 分钟，期望 2、2、1", stored.CharCount, stored.WordCount, stored.ReadingTime)
+	}
+}
+
+func TestGetPostByIDDoesNotWrite(t *testing.T) {
+	openTestDB(t)
+	post := insertUncountedPost(t, "<p>Hello world 你好</p>")
+
+	loaded, err := GetPostByID(int(post.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Words() != 4 || loaded.ReadingTime != 1 {
+		t.Errorf("读取时统计 = %d 字、%d 分钟，期望 4、1", loaded.Words(), loaded.ReadingTime)
+	}
+	// 读取只在内存中统计，由定时补全写入
+	if n := countUncounted(t); n != 1 {
+		t.Errorf("读取后未统计的文章数 = %d，期望 1", n)
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SearchDocument 搜索索引文档，Body 为提取后的纯文本，全文索引使用 ngram 解析器以支持中文分词
+type SearchDocument struct {
+	PostID          uint      `gorm:"primarykey;autoIncrement:false;comment:文章ID"`
+	Title           string    `gorm:"size:200;index:idx_search_fulltext,class:FULLTEXT,option:WITH PARSER ngram;comment:文章标题"`
+	Summary         string    `gorm:"size:500;index:idx_search_fulltext,class:FULLTEXT,option:WITH PARSER ngram;comment:文章摘要"`
+	Body            string    `gorm:"type:longtext;index:idx_search_fulltext,class:FULLTEXT,option:WITH PARSER ngram;comment:文章纯文本"`
+	SourceUpdatedAt time.Time `gorm:"comment:文章更新时间"`
+}
+
+// SearchResult 全文检索结果
+type SearchResult struct {
+	SearchDocument
+	Score float64
+}
+
+const matchExpr = "MATCH(search_documents.title, search_documents.summary, search_documents.body) AGAINST(? IN NATURAL LANGUAGE MODE)"
+
+// 只检索仍处于发布状态的文章
+func searchableDocuments(query string) *gorm.DB {
+	return DB.Table("search_documents").
+		Joins("JOIN posts ON posts.id = search_documents.post_id AND posts.deleted_at IS NULL AND posts.status = ?", PostStatusPublished).
+		Where(matchExpr, query)
+}
+
+// SearchDocuments 全文检索，按相关度排序
+func SearchDocuments(query string, offset, limit int) ([]SearchResult, int64, error) {
+	var total int64
+	if err := searchableDocuments(query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var results []SearchResult
+	err := searchableDocuments(query).
+		Select("search_documents.*, "+matchExpr+" AS score", query).
+		Order("score desc").
+		Offset(offset).
+		Limit(limit).
+		Scan(&results).Error
+	return results, total, err
+}
+
+// SaveSearchDocument 新增或更新索引文档
+func SaveSearchDocument(doc *SearchDocument) error {
+	return DB.Save(doc).Error
+}
+
+// DeleteSearchDocument 删除索引文档
+func DeleteSearchDocument(postID uint) error {
+	return DB.Delete(&SearchDocument{}, postID).Error
+}
+
+// GetSearchDocumentVersions 获取已索引文章对应的文章更新时间
+func GetSearchDocumentVersions() (map[uint]time.Time, error) {
+	var docs []SearchDocument
+	if err := DB.Select("post_id, source_updated_at").Find(&docs).Error; err != nil {
+		return nil, err
+	}
+	versions := make(map[uint]time.Time, len(docs))
+	for _, d := range docs {
+		versions[d.PostID] = d.SourceUpdatedAt
+	}
+	return versions, nil
+}
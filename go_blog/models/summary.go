@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostSummary AI 摘要缓存，文章内容、模型或提示词变化后自动失效
+type PostSummary struct {
+	ID          uint      `gorm:"primarykey;comment:摘要ID"`
+	PostID      uint      `gorm:"uniqueIndex:idx_summary_key;comment:文章ID"`
+	ContentHash string    `gorm:"size:64;uniqueIndex:idx_summary_key;comment:文章内容哈希"`
+	Model       string    `gorm:"size:100;uniqueIndex:idx_summary_key;comment:模型"`
+	PromptHash  string    `gorm:"size:64;uniqueIndex:idx_summary_key;comment:提示词哈希"`
+	Summary     string    `gorm:"type:longtext;comment:摘要内容"`
+	CreatedAt   time.Time `gorm:"comment:生成时间"`
+}
+
+// HashText 计算文本的 SHA-256 哈希
+func HashText(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetCachedSummary 查找缓存的摘要，未命中时返回 nil
+func GetCachedSummary(postID uint, contentHash, model, promptHash string) (*PostSummary, error) {
+	var summary PostSummary
+	err := DB.Where("post_id = ? AND content_hash = ? AND model = ? AND prompt_hash = ?",
+		postID, contentHash, model, promptHash).
+		First(&summary).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// SaveSummary 保存摘要，相同缓存键已存在时覆盖
+func SaveSummary(summary *PostSummary) error {
+	return DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"summary", "created_at"}),
+	}).Create(summary).Error
+}
+
+// DeletePostSummaries 删除文章的所有缓存摘要，下次请求时重新生成
+func DeletePostSummaries(postID uint) error {
+	return DB.Where("post_id = ?", postID).Delete(&PostSummary{}).Error
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"errors"
+	"go_blog/apperr"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Tag 文章标签，与文章多对多关联（关联表 post_tags）
+type Tag struct {
+	ID        uint      `gorm:"primarykey;comment:标签ID"`
+	Name      string    `gorm:"size:50;not null;uniqueIndex;comment:标签名"`
+	CreatedAt time.Time `gorm:"comment:创建时间"`
+}
+
+// TagCount 标签及其已发布文章数
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// 标签名的最大长度（字符数）
+const maxTagLength = 50
+
+var (
+	// ErrInvalidTag 标签名为空或过长
+	ErrInvalidTag = apperr.NewInvalid("invalid_tag", "标签名不能为空且不能超过 50 个字符")
+	// ErrTagNotFound 标签不存在
+	ErrTagNotFound = apperr.NewNotFound("not_found", "标签不存在")
+)
+
+// NormalizeTag 规范化标签名：去掉首尾空白、合并连续空白并转为小写
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ParseTags 解析以逗号（包括中文逗号）分隔的标签，去掉空标签和重复标签
+func ParseTags(s string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' }) {
+		name := NormalizeTag(part)
+		if name == "" || utf8.RuneCountInString(name) > maxTagLength || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// TagNames 文章的标签名列表
+func (p *Post) TagNames() []string {
+	names := make([]string, 0, len(p.Tags))
+	for _, t := range p.Tags {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// SetTags 按名称设置文章标签，保存文章时写入关联表
+func (p *Post) SetTags(names []string) {
+	p.Tags = make([]Tag, 0, len(names))
+	for _, name := range names {
+		p.Tags = append(p.Tags, Tag{Name: name})
+	}
+}
+
+// 按名称查找或创建标签，并替换文章的标签关联
+func saveTags(tx *gorm.DB, post *Post) error {
+	tags := make([]Tag, 0, len(post.Tags))
+	for _, t := range post.Tags {
+		tag := Tag{Name: t.Name}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("name = ?", t.Name).First(&tag).Error; err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+	post.Tags = tags
+	return tx.Model(post).Association("Tags").Replace(tags)
+}
+
+// 只查询带有全部指定标签的文章
+func withTags(names []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(names) == 0 {
+			return db
+		}
+		sub := DB.Table("post_tags").
+			Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name IN ?", names).
+			Group("post_tags.post_id").
+			Having("COUNT(DISTINCT tags.id) = ?", len(names))
+		return db.Where("posts.id IN (?)", sub)
+	}
+}
+
+// GetTagCounts 获取标签及其已发布文章数，按文章数降序，limit 为 0 时返回全部
+func GetTagCounts(limit int) ([]TagCount, error) {
+	var counts []TagCount
+	query := DB.Table("tags").
+		Select("tags.name, COUNT(posts.id) AS count").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL AND posts.status = ?", PostStatusPublished).
+		Group("tags.id, tags.name").
+		Order("count DESC, tags.name")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Scan(&counts).Error
+	return counts, err
+}
+
+// AdminGetTags 后台获取所有标签及其文章数（包括未发布的文章）
+func AdminGetTags() ([]TagCount, error) {
+	var counts []TagCount
+	err := DB.Table("tags").
+		Select("tags.name, COUNT(posts.id) AS count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL").
+		Group("tags.id, tags.name").
+		Order("tags.name").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// RenameTag 重命名标签，新名称已存在时合并到该标签
+func RenameTag(from, to string) error {
+	to = NormalizeTag(to)
+	if to == "" || utf8.RuneCountInString(to) > maxTagLength {
+		return ErrInvalidTag
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var source Tag
+		if err := tx.Where("name = ?", NormalizeTag(from)).First(&source).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+
+		var target Tag
+		err := tx.Where("name = ?", to).First(&target).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Model(&source).Update("name", to).Error
+		}
+		if err != nil {
+			return err
+		}
+		if target.ID == source.ID {
+			// 只有大小写或空白不同
+			return tx.Model(&source).Update("name", to).Error
+		}
+		return mergeTag(tx, &source, &target)
+	})
+}
+
+// MergeTags 将多个标签合并到目标标签，目标标签不存在时创建
+func MergeTags(from []string, to string) error {
+	to = NormalizeTag(to)
+	if to == "" || utf8.RuneCountInString(to) > maxTagLength {
+		return ErrInvalidTag
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		target := Tag{Name: to}
+		if err := tx.Where("name = ?", to).FirstOrCreate(&target).Error; err != nil {
+			return err
+		}
+		for _, name := range from {
+			var source Tag
+			err := tx.Where("name = ?", NormalizeTag(name)).First(&source).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if source.ID == target.ID {
+				continue
+			}
+			if err := mergeTag(tx, &source, &target); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 把 source 的文章关联转移到 target 后删除 source，已同时带有两个标签的文章只保留一条关联
+func mergeTag(tx *gorm.DB, source, target *Tag) error {
+	err := tx.Exec(`INSERT IGNORE INTO post_tags (post_id, tag_id)
+		SELECT post_id, ? FROM post_tags WHERE tag_id = ?`, target.ID, source.ID).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", source.ID).Error; err != nil {
+		return err
+	}
+	return tx.Delete(source).Error
+}
+
+// DeleteTag 删除标签及其文章关联
+func DeleteTag(name string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var tag Tag
+		if err := tx.Where("name = ?", NormalizeTag(name)).First(&tag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTagNotFound
+			}
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+}
+
+// go_cli 写入的文章表，标签以逗号分隔保存在 tags 字段中
+const articlesTable = "articles"
+
+// ImportArticleTags 将 go_cli 文章表中的标签导入到同名文章，返回导入了标签的文章数。
+// 只处理还没有任何标签的文章，重复执行不会覆盖后台修改过的标签；没有文章表时不做任何事
+func ImportArticleTags() (int, error) {
+	if !DB.Migrator().HasTable(articlesTable) {
+		return 0, nil
+	}
+
+	var articles []struct {
+		Title string
+		Tags  string
+	}
+	err := DB.Table(articlesTable).Select("title, tags").
+		Where("tags IS NOT NULL AND tags <> ''").
+		Find(&articles).Error
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, a := range articles {
+		names := ParseTags(a.Tags)
+		if len(names) == 0 {
+			continue
+		}
+		var posts []Post
+		err := DB.Select("id").
+			Where("title = ?", a.Title).
+			Where("id NOT IN (?)", DB.Table("post_tags").Select("post_id")).
+			Find(&posts).Error
+		if err != nil {
+			return imported, err
+		}
+		for i := range posts {
+			post := &posts[i]
+			post.SetTags(names)
+			if err := DB.Transaction(func(tx *gorm.DB) error { return saveTags(tx, post) }); err != nil {
+				return imported, err
+			}
+			imported++
+		}
+	}
+	return imported, nil
+}
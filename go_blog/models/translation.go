@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostTranslation 文章的 AI 译文，每篇文章每种语言保存一份，原文变化后失效
+type PostTranslation struct {
+	ID         uint      `gorm:"primarykey;comment:译文ID"`
+	PostID     uint      `gorm:"uniqueIndex:idx_translation_key;comment:文章ID"`
+	Lang       string    `gorm:"size:10;uniqueIndex:idx_translation_key;comment:目标语言"`
+	SourceHash string    `gorm:"size:64;comment:原文哈希"`
+	Model      string    `gorm:"size:100;comment:模型"`
+	Title      string    `gorm:"size:200;comment:译文标题"`
+	Summary    string    `gorm:"size:500;comment:译文摘要"`
+	Content    string    `gorm:"type:longtext;comment:译文内容"`
+	CreatedAt  time.Time `gorm:"comment:翻译时间"`
+}
+
+// 译文标题和摘要的最大长度（字符数），与字段长度一致
+const (
+	maxTranslationTitleLength   = 200
+	maxTranslationSummaryLength = 500
+)
+
+// SourceHash 计算文章原文（标题、摘要、正文）的哈希，用于判断译文是否过期
+func (p *Post) SourceHash() string {
+	return HashText(p.Title + "\x00" + p.Summary + "\x00" + p.Content)
+}
+
+// ApplyTranslation 用译文替换文章的标题、摘要和正文，译文始终是 HTML
+func (p *Post) ApplyTranslation(t *PostTranslation) {
+	p.Title = t.Title
+	p.Summary = t.Summary
+	p.Content = t.Content
+	p.ContentFormat = ContentFormatHTML
+	p.HTMLContent = ""
+	p.updateReadingStats()
+	p.renderContent()
+}
+
+// GetTranslation 查找与当前原文一致的译文，未翻译或原文已变化时返回 nil
+func GetTranslation(postID uint, lang, sourceHash string) (*PostTranslation, error) {
+	var t PostTranslation
+	err := DB.Where("post_id = ? AND lang = ? AND source_hash = ?", postID, lang, sourceHash).
+		First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTranslation 保存译文，覆盖该语言过期的译文。
+// 模型返回的标题和摘要可能超过字段长度，按字符截断，避免严格模式下写入失败
+func SaveTranslation(t *PostTranslation) error {
+	t.Title = truncateRunes(t.Title, maxTranslationTitleLength)
+	t.Summary = truncateRunes(t.Summary, maxTranslationSummaryLength)
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}, {Name: "lang"}},
+		DoUpdates: clause.AssignmentColumns([]string{"source_hash", "model", "title", "summary", "content", "created_at"}),
+	}).Create(t).Error
+}
+
+// 按字符数截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// DeletePostTranslations 删除文章的所有译文
+func DeletePostTranslations(postID uint) error {
+	return DB.Where("post_id = ?", postID).Delete(&PostTranslation{}).Error
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSaveTranslationTruncatesLongFields(t *testing.T) {
+	openTestDB(t)
+	post := createTestPost(t, &Post{Title: "原文", Content: "<p>正文</p>"})
+
+	tr := &PostTranslation{
+		PostID:     post.ID,
+		Lang:       "en",
+		SourceHash: post.SourceHash(),
+		Title:      strings.Repeat("标", maxTranslationTitleLength+50),
+		Summary:    strings.Repeat("摘", maxTranslationSummaryLength+50),
+		Content:    "<p>body</p>",
+	}
+	if err := SaveTranslation(tr); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := GetTranslation(post.ID, "en", post.SourceHash())
+	if err != nil || saved == nil {
+		t.Fatalf("GetTranslation = %v, %v", saved, err)
+	}
+	if n := utf8.RuneCountInString(saved.Title); n != maxTranslationTitleLength {
+		t.Errorf("标题长度 = %d，期望 %d", n, maxTranslationTitleLength)
+	}
+	if n := utf8.RuneCountInString(saved.Summary); n != maxTranslationSummaryLength {
+		t.Errorf("摘要长度 = %d，期望 %d", n, maxTranslationSummaryLength)
+	}
+	if !utf8.ValidString(saved.Title) || !utf8.ValidString(saved.Summary) {
+		t.Error("截断后不是合法的 UTF-8")
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AIUsage 每次调用 AI 服务的用量记录
+type AIUsage struct {
+	ID               uint      `gorm:"primarykey;comment:记录ID"`
+	CreatedAt        time.Time `gorm:"index;comment:调用时间"`
+	Feature          string    `gorm:"size:20;index;comment:功能"`
+	PostID           uint      `gorm:"index;comment:文章ID"`
+	Provider         string    `gorm:"size:20;comment:服务商"`
+	Model            string    `gorm:"size:100;comment:模型"`
+	PromptTokens     int       `gorm:"comment:输入token数"`
+	CompletionTokens int       `gorm:"comment:输出token数"`
+	Estimated        bool      `gorm:"comment:是否为本地估算的用量"`
+	LatencyMs        int64     `gorm:"comment:耗时（毫秒）"`
+	Success          bool      `gorm:"comment:是否成功"`
+	Cost             float64   `gorm:"comment:费用"`
+}
+
+// AIUsageDaily 按天、功能和模型汇总的用量
+type AIUsageDaily struct {
+	Day              time.Time `gorm:"type:date;primaryKey;comment:日期"`
+	Feature          string    `gorm:"size:20;primaryKey;comment:功能"`
+	Model            string    `gorm:"size:100;primaryKey;comment:模型"`
+	Calls            int64     `gorm:"comment:调用次数"`
+	PromptTokens     int64     `gorm:"comment:输入token数"`
+	CompletionTokens int64     `gorm:"comment:输出token数"`
+	Cost             float64   `gorm:"comment:费用"`
+}
+
+// RecordUsage 写入用量记录并累加到当日汇总
+func RecordUsage(usage *AIUsage) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+
+		y, m, d := usage.CreatedAt.Date()
+		daily := &AIUsageDaily{
+			Day:              time.Date(y, m, d, 0, 0, 0, 0, usage.CreatedAt.Location()),
+			Feature:          usage.Feature,
+			Model:            usage.Model,
+			Calls:            1,
+			PromptTokens:     int64(usage.PromptTokens),
+			CompletionTokens: int64(usage.CompletionTokens),
+			Cost:             usage.Cost,
+		}
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"calls":             gorm.Expr("calls + ?", daily.Calls),
+				"prompt_tokens":     gorm.Expr("prompt_tokens + ?", daily.PromptTokens),
+				"completion_tokens": gorm.Expr("completion_tokens + ?", daily.CompletionTokens),
+				"cost":              gorm.Expr("cost + ?", daily.Cost),
+			}),
+		}).Create(daily).Error
+	})
+}
+
+// GetDailyUsage 获取指定日期之后的每日汇总，按日期倒序
+func GetDailyUsage(since time.Time) ([]AIUsageDaily, error) {
+	var rows []AIUsageDaily
+	err := DB.Where("day >= ?", since).
+		Order("day desc, feature, model").
+		Find(&rows).Error
+	return rows, err
+}
+
+// GetCostSince 获取指定日期之后的总费用
+func GetCostSince(since time.Time) (float64, error) {
+	var cost float64
+	err := DB.Model(&AIUsageDaily{}).
+		Where("day >= ?", since).
+		Select("COALESCE(SUM(cost), 0)").
+		Scan(&cost).Error
+	return cost, err
+}
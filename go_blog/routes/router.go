@@ -2,6 +2,7 @@ package routes
 
 import (
 	"go_blog/controllers"
+	"go_blog/models"
 	"go_blog/utils"
 	"html/template"
 	"os"
@@ -17,11 +18,16 @@ func SetupRouter() *gin.Engine {
 	}
 	r := gin.Default()
 
+	// 只信任配置的反向代理，避免伪造 X-Forwarded-For 绕过按 IP 限流
+	if err := r.SetTrustedProxies(utils.AppConfig.Server.TrustedProxies); err != nil {
+		panic(err)
+	}
+
 	// 初始化日志
 	utils.InitLogger()
 
-	// 添加日志中间件
-	r.Use(utils.GinLogger())
+	// 添加请求ID和日志中间件
+	r.Use(utils.RequestID(), utils.GinLogger())
 
 	// 添加自定义模板函数
 	r.SetFuncMap(template.FuncMap{
@@ -58,8 +64,84 @@ func SetupRouter() *gin.Engine {
 	// 设置路由
 	r.GET("/", controllers.PostList)
 	r.GET("/category/:category", controllers.PostList)
+	r.GET("/tag/:tag", controllers.PostList)
+	r.GET("/archive", controllers.Archive)
+	r.GET("/archive/:year", controllers.ArchiveList)
+	r.GET("/archive/:year/:month", controllers.ArchiveList)
 	r.GET("/post/:id", controllers.PostDetail)
-	r.POST("/post/:id/summary", controllers.GeneratePostSummary)
+	r.POST("/post/:id/summary", controllers.SameOrigin(), controllers.SummaryRateLimit(), controllers.GeneratePostSummary)
+	r.POST("/post/:id/chat", controllers.SameOrigin(), controllers.ChatRateLimit(), controllers.PostChat)
+	r.DELETE("/post/:id/chat", controllers.SameOrigin(), controllers.ResetPostChat)
+	r.GET("/search", controllers.SearchRateLimit(), controllers.Search)
+
+	// 代码高亮主题
+	r.GET("/highlight.css", controllers.HighlightCSS)
+
+	// 监控指标
+	r.GET("/metrics", controllers.Metrics)
+
+	// 订阅源
+	r.GET("/feed.xml", controllers.RSSFeed)
+	r.GET("/atom.xml", controllers.AtomFeed)
+	r.GET("/category/:category/feed.xml", controllers.RSSFeed)
+
+	// JSON 接口
+	v1 := r.Group("/api/v1")
+	{
+		v1.GET("/posts", controllers.APIPostList)
+		v1.GET("/posts/:id", controllers.APIPostDetail)
+		v1.POST("/posts/:id/summary", controllers.SameOrigin(), controllers.SummaryRateLimit(), controllers.GeneratePostSummary)
+		v1.POST("/posts/:id/chat", controllers.SameOrigin(), controllers.ChatRateLimit(), controllers.PostChat)
+		v1.DELETE("/posts/:id/chat", controllers.SameOrigin(), controllers.ResetPostChat)
+		v1.GET("/categories", controllers.APICategoryList)
+		v1.GET("/tags", controllers.APITagList)
+		v1.GET("/search", controllers.SearchRateLimit(), controllers.APISearch)
+
+		v1.POST("/admin/login", controllers.LoginRateLimit(), controllers.APIAdminLogin)
+		adminAPI := v1.Group("/admin", controllers.APIAdminRequired())
+		{
+			adminAPI.POST("/logout", controllers.APIAdminLogout)
+			adminAPI.GET("/posts", controllers.APIAdminPostList)
+			adminAPI.POST("/posts", controllers.APIAdminCreatePost)
+			adminAPI.PUT("/posts/:id", controllers.APIAdminUpdatePost)
+			adminAPI.POST("/posts/:id/publish", controllers.APIAdminSetPostStatus(models.PostStatusPublished))
+			adminAPI.POST("/posts/:id/unpublish", controllers.APIAdminSetPostStatus(models.PostStatusDraft))
+			adminAPI.DELETE("/posts/:id", controllers.APIAdminDeletePost)
+			adminAPI.DELETE("/posts/:id/summary", controllers.APIAdminClearSummary)
+			adminAPI.GET("/usage", controllers.APIAdminUsage)
+			adminAPI.GET("/tags", controllers.APIAdminTagList)
+			adminAPI.POST("/tags/merge", controllers.APIAdminMergeTags)
+			adminAPI.POST("/tags/import", controllers.APIAdminImportTags)
+			adminAPI.PUT("/tags/:tag", controllers.APIAdminRenameTag)
+			adminAPI.DELETE("/tags/:tag", controllers.APIAdminDeleteTag)
+		}
+	}
+
+	// 后台管理
+	r.GET("/admin/login", controllers.AdminLoginPage)
+	r.POST("/admin/login", controllers.LoginRateLimit(), controllers.AdminLogin)
+	admin := r.Group("/admin", controllers.AdminRequired())
+	{
+		admin.GET("", controllers.AdminPostList)
+		admin.POST("/logout", controllers.AdminLogout)
+		admin.GET("/posts/new", controllers.AdminNewPost)
+		admin.POST("/posts", controllers.AdminCreatePost)
+		admin.GET("/posts/:id/edit", controllers.AdminEditPost)
+		admin.POST("/posts/:id", controllers.AdminUpdatePost)
+		admin.POST("/posts/:id/publish", controllers.AdminSetPostStatus(models.PostStatusPublished))
+		admin.POST("/posts/:id/unpublish", controllers.AdminSetPostStatus(models.PostStatusDraft))
+		admin.POST("/posts/:id/delete", controllers.AdminDeletePost)
+		admin.POST("/posts/:id/summary/refresh", controllers.AdminClearSummary)
+		admin.GET("/usage", controllers.AdminUsage)
+		admin.GET("/tags", controllers.AdminTagList)
+		admin.POST("/tags/rename", controllers.AdminRenameTag)
+		admin.POST("/tags/merge", controllers.AdminMergeTags)
+		admin.POST("/tags/import", controllers.AdminImportTags)
+		admin.POST("/tags/delete", controllers.AdminDeleteTag)
+	}
+
+	// 未匹配的路由
+	r.NoRoute(controllers.NoRoute)
 
 	return r
 }
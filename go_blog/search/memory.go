@@ -0,0 +1,184 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// 字段权重：标题 > 摘要 > 正文
+const (
+	titleWeight   = 3.0
+	summaryWeight = 2.0
+	bodyWeight    = 1.0
+)
+
+// BM25 参数
+const bm25K1 = 1.2
+
+// 单篇文章中某个词在各字段出现的次数
+type posting struct {
+	title, summary, body int
+}
+
+// memoryIndex 倒排索引
+type memoryIndex struct {
+	docs     map[uint]Document
+	postings map[string]map[uint]*posting
+}
+
+// MemoryEngine 进程内倒排索引，适合文章数量不大的场景
+type MemoryEngine struct {
+	mu    sync.RWMutex
+	index *memoryIndex
+}
+
+// NewMemoryEngine 创建进程内搜索后端
+func NewMemoryEngine() *MemoryEngine {
+	return &MemoryEngine{index: newMemoryIndex()}
+}
+
+func newMemoryIndex() *memoryIndex {
+	return &memoryIndex{
+		docs:     make(map[uint]Document),
+		postings: make(map[string]map[uint]*posting),
+	}
+}
+
+func (idx *memoryIndex) add(doc Document) {
+	idx.remove(doc.ID)
+	idx.docs[doc.ID] = doc
+
+	get := func(token string) *posting {
+		list, ok := idx.postings[token]
+		if !ok {
+			list = make(map[uint]*posting)
+			idx.postings[token] = list
+		}
+		p, ok := list[doc.ID]
+		if !ok {
+			p = &posting{}
+			list[doc.ID] = p
+		}
+		return p
+	}
+	for _, t := range Tokenize(doc.Title) {
+		get(t).title++
+	}
+	for _, t := range Tokenize(doc.Summary) {
+		get(t).summary++
+	}
+	for _, t := range Tokenize(doc.Body) {
+		get(t).body++
+	}
+}
+
+func (idx *memoryIndex) remove(id uint) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	delete(idx.docs, id)
+	for _, text := range []string{doc.Title, doc.Summary, doc.Body} {
+		for _, t := range Tokenize(text) {
+			if list, ok := idx.postings[t]; ok {
+				delete(list, id)
+				if len(list) == 0 {
+					delete(idx.postings, t)
+				}
+			}
+		}
+	}
+}
+
+// Index 新增或更新一篇文章的索引
+func (e *MemoryEngine) Index(doc Document) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.index.add(doc)
+	return nil
+}
+
+// Remove 删除一篇文章的索引
+func (e *MemoryEngine) Remove(id uint) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.index.remove(id)
+	return nil
+}
+
+// Replace 构建新索引后整体替换，重建期间不影响查询
+func (e *MemoryEngine) Replace(docs []Document) error {
+	idx := newMemoryIndex()
+	for _, doc := range docs {
+		idx.add(doc)
+	}
+	e.mu.Lock()
+	e.index = idx
+	e.mu.Unlock()
+	return nil
+}
+
+// Search 使用 BM25 按字段加权计算相关度
+func (e *MemoryEngine) Search(query string, offset, limit int) ([]Hit, int64, error) {
+	tokens := uniqueTokens(query)
+	if len(tokens) == 0 {
+		return nil, 0, nil
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	idx := e.index
+
+	n := float64(len(idx.docs))
+	scores := make(map[uint]float64)
+	for _, t := range tokens {
+		list := idx.postings[t]
+		if len(list) == 0 {
+			continue
+		}
+		df := float64(len(list))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, p := range list {
+			tf := titleWeight*float64(p.title) + summaryWeight*float64(p.summary) + bodyWeight*float64(p.body)
+			scores[id] += idf * tf * (bm25K1 + 1) / (tf + bm25K1)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+
+	total := int64(len(hits))
+	if offset >= len(hits) {
+		return nil, total, nil
+	}
+	hits = hits[offset:]
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	for i := range hits {
+		highlightHit(&hits[i], idx.docs[hits[i].ID], tokens)
+	}
+	return hits, total, nil
+}
+
+// 对查询分词并去重
+func uniqueTokens(query string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, t := range Tokenize(query) {
+		if !seen[t] {
+			seen[t] = true
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
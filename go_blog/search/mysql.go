@@ -0,0 +1,86 @@
+package search
+
+import (
+	"go_blog/models"
+)
+
+// MySQLEngine 基于 MySQL FULLTEXT（ngram 解析器）的搜索后端，索引数据保存在 search_documents 表
+type MySQLEngine struct{}
+
+// NewMySQLEngine 创建 MySQL 搜索后端
+func NewMySQLEngine() *MySQLEngine {
+	return &MySQLEngine{}
+}
+
+func toSearchDocument(doc Document) *models.SearchDocument {
+	return &models.SearchDocument{
+		PostID:          doc.ID,
+		Title:           doc.Title,
+		Summary:         doc.Summary,
+		Body:            doc.Body,
+		SourceUpdatedAt: doc.UpdatedAt,
+	}
+}
+
+// Index 新增或更新一篇文章的索引
+func (e *MySQLEngine) Index(doc Document) error {
+	return models.SaveSearchDocument(toSearchDocument(doc))
+}
+
+// Remove 删除一篇文章的索引
+func (e *MySQLEngine) Remove(id uint) error {
+	return models.DeleteSearchDocument(id)
+}
+
+// Replace 只写入有变化的文章，并删除已不存在的文章
+func (e *MySQLEngine) Replace(docs []Document) error {
+	versions, err := models.GetSearchDocumentVersions()
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[uint]bool, len(docs))
+	for _, doc := range docs {
+		keep[doc.ID] = true
+		if v, ok := versions[doc.ID]; ok && v.Equal(doc.UpdatedAt) {
+			continue
+		}
+		if err := e.Index(doc); err != nil {
+			return err
+		}
+	}
+	for id := range versions {
+		if !keep[id] {
+			if err := e.Remove(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Search 使用 MATCH ... AGAINST 自然语言模式查询
+func (e *MySQLEngine) Search(query string, offset, limit int) ([]Hit, int64, error) {
+	tokens := uniqueTokens(query)
+	if len(tokens) == 0 {
+		return nil, 0, nil
+	}
+
+	results, total, err := models.SearchDocuments(query, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]Hit, 0, len(results))
+	for _, r := range results {
+		hit := Hit{ID: r.PostID, Score: r.Score}
+		highlightHit(&hit, Document{
+			ID:      r.PostID,
+			Title:   r.Title,
+			Summary: r.Summary,
+			Body:    r.Body,
+		}, tokens)
+		hits = append(hits, hit)
+	}
+	return hits, total, nil
+}
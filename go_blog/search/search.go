@@ -0,0 +1,122 @@
+package search
+
+import (
+	"fmt"
+	"go_blog/models"
+	"go_blog/utils"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// 片段截取长度（字符数）
+const snippetWidth = 160
+
+// Document 待索引的文章内容
+type Document struct {
+	ID        uint
+	Title     string
+	Summary   string
+	Body      string
+	UpdatedAt time.Time
+}
+
+// Hit 搜索命中结果
+type Hit struct {
+	ID      uint
+	Score   float64
+	Title   template.HTML
+	Snippet template.HTML
+}
+
+// Engine 搜索后端
+type Engine interface {
+	// Index 新增或更新一篇文章的索引
+	Index(doc Document) error
+	// Remove 删除一篇文章的索引
+	Remove(id uint) error
+	// Replace 使用全部文章重建索引
+	Replace(docs []Document) error
+	// Search 按相关度返回命中结果和命中总数
+	Search(query string, offset, limit int) ([]Hit, int64, error)
+}
+
+// Default 当前使用的搜索后端
+var Default Engine
+
+// Init 根据配置初始化搜索后端，完成首次建索引并启动定时重建
+func Init() error {
+	switch strings.ToLower(utils.AppConfig.Search.Backend) {
+	case "", "memory":
+		Default = NewMemoryEngine()
+	case "mysql":
+		Default = NewMySQLEngine()
+	default:
+		return fmt.Errorf("未知的搜索后端: %s", utils.AppConfig.Search.Backend)
+	}
+
+	if err := Rebuild(); err != nil {
+		return err
+	}
+
+	// 爬虫等外部程序会直接写数据库，定时重建以保持索引同步
+	if interval := utils.AppConfig.Search.RebuildInterval; interval > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+			defer ticker.Stop()
+			for range ticker.C {
+				if err := Rebuild(); err != nil {
+					utils.Log.WithError(err).Error("重建搜索索引失败")
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// NewDocument 从文章生成索引文档
+func NewDocument(post *models.Post) Document {
+	return Document{
+		ID:        post.ID,
+		Title:     post.Title,
+		Summary:   post.Summary,
+		Body:      utils.ExtractText(post.SourceHTML()),
+		UpdatedAt: post.UpdatedAt,
+	}
+}
+
+// Rebuild 从数据库读取所有已发布文章并重建索引
+func Rebuild() error {
+	var docs []Document
+	err := models.EachPublishedPost(func(post *models.Post) error {
+		docs = append(docs, NewDocument(post))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return Default.Replace(docs)
+}
+
+// IndexPost 文章变更后同步索引，未发布或已删除的文章会从索引中移除
+func IndexPost(id uint) {
+	if Default == nil {
+		return
+	}
+	var err error
+	post, getErr := models.GetPostByID(int(id))
+	if getErr != nil {
+		err = Default.Remove(id)
+	} else {
+		err = Default.Index(NewDocument(post))
+	}
+	if err != nil {
+		utils.Log.WithError(err).WithField("post_id", id).Error("更新搜索索引失败")
+	}
+}
+
+// 生成带高亮的标题和片段
+func highlightHit(hit *Hit, doc Document, tokens []string) {
+	hit.Title = Highlight(doc.Title, tokens, 0)
+	hit.Snippet = Highlight(strings.TrimSpace(doc.Summary+" "+doc.Body), tokens, snippetWidth)
+}
@@ -0,0 +1,157 @@
+package transform
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 锚点的最大长度（字符数）
+const maxSlugLength = 64
+
+// 文章页中已使用的元素 ID，标题锚点需要避开
+var reservedIDs = map[string]bool{
+	"chat": true, "related": true, "toc": true,
+}
+
+// 生成目录的标题级别
+var tocLevels = map[string]int{"h2": 2, "h3": 3, "h4": 4}
+
+// headingAnchors 为 h2–h4 标题生成锚点 ID，并在标题后添加锚点链接。
+// ID 由标题文字生成，中文等非拉丁文字原样保留，同一篇文章中重复的标题依次添加 -2、-3 后缀
+type headingAnchors struct{}
+
+func (headingAnchors) Name() string { return "heading-anchors" }
+
+func (headingAnchors) Transform(root *html.Node, ctx *Context) {
+	used := make(map[string]bool)
+	for id := range reservedIDs {
+		used[id] = true
+	}
+	// 原文中已有的 ID 保持不变
+	walkElements(root, func(n *html.Node) bool {
+		if id, ok := getAttr(n, "id"); ok && id != "" {
+			used[id] = true
+		}
+		return true
+	})
+
+	walkElements(root, func(n *html.Node) bool {
+		if _, ok := tocLevels[n.Data]; !ok {
+			return true
+		}
+		id, ok := getAttr(n, "id")
+		if !ok || id == "" {
+			id = uniqueSlug(slugify(textContent(n)), used)
+			setAttr(n, "id", id)
+		}
+
+		anchor := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A}
+		setAttr(anchor, "class", "heading-anchor")
+		setAttr(anchor, "href", "#"+id)
+		setAttr(anchor, "aria-hidden", "true")
+		anchor.AppendChild(&html.Node{Type: html.TextNode, Data: "#"})
+		n.AppendChild(anchor)
+		return false
+	})
+}
+
+// 由标题文字生成锚点：字母和数字（包括汉字）转为小写保留，其余字符合并为一个连字符
+func slugify(text string) string {
+	var b strings.Builder
+	count := 0
+	separator := false
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			separator = true
+			continue
+		}
+		if count >= maxSlugLength {
+			break
+		}
+		if separator && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		separator = false
+		b.WriteRune(r)
+		count++
+	}
+	if b.Len() == 0 {
+		return "section"
+	}
+	return b.String()
+}
+
+func uniqueSlug(slug string, used map[string]bool) string {
+	id := slug
+	for i := 2; used[id]; i++ {
+		id = slug + "-" + strconv.Itoa(i)
+	}
+	used[id] = true
+	return id
+}
+
+// TOCEntry 文章目录项，子标题按层级嵌套
+type TOCEntry struct {
+	ID       string
+	Title    string
+	Level    int
+	Children []*TOCEntry
+}
+
+// TOC 从处理后的正文中提取带 ID 的 h2–h4 标题，生成嵌套目录
+func TOC(content string) []*TOCEntry {
+	root := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), root)
+	if err != nil {
+		return nil
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	var entries []*TOCEntry
+	var stack []*TOCEntry
+	walkElements(root, func(n *html.Node) bool {
+		level, ok := tocLevels[n.Data]
+		if !ok {
+			return true
+		}
+		id, _ := getAttr(n, "id")
+		title := strings.Join(strings.Fields(headingText(n)), " ")
+		if id == "" || title == "" {
+			return false
+		}
+
+		entry := &TOCEntry{ID: id, Title: title, Level: level}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			entries = append(entries, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+		stack = append(stack, entry)
+		return false
+	})
+	return entries
+}
+
+// 标题文字，不包含锚点链接
+func headingText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "a" {
+			if class, _ := getAttr(c, "class"); class == "heading-anchor" {
+				continue
+			}
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
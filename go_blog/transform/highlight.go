@@ -0,0 +1,158 @@
+package transform
+
+import (
+	"bytes"
+	"go_blog/utils"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	defaultHighlightStyle = "github"
+	defaultTabWidth       = 4
+)
+
+// highlighter 对带有 language-x 类名的代码块做服务端语法高亮。
+// 高亮后的代码块包在 <div class="highlight" data-lang="x"> 中，页面脚本据此添加复制按钮
+type highlighter struct {
+	formatter *chromahtml.Formatter
+	style     *chroma.Style
+}
+
+func newHighlighter(cfg utils.HighlightConfig) *highlighter {
+	name := cfg.Style
+	if name == "" {
+		name = defaultHighlightStyle
+	}
+	style := styles.Get(name)
+	if style == styles.Fallback && name != styles.Fallback.Name && utils.Log != nil {
+		utils.Log.WithField("style", name).Warn("未知的代码高亮主题，使用默认主题")
+	}
+
+	tabWidth := cfg.TabWidth
+	if tabWidth <= 0 {
+		tabWidth = defaultTabWidth
+	}
+	return &highlighter{
+		formatter: chromahtml.New(
+			chromahtml.WithClasses(true),
+			chromahtml.WithLineNumbers(cfg.LineNumbers),
+			chromahtml.TabWidth(tabWidth),
+		),
+		style: style,
+	}
+}
+
+func (h *highlighter) Name() string { return "highlight" }
+
+func (h *highlighter) Transform(root *html.Node, ctx *Context) {
+	walkElements(root, func(n *html.Node) bool {
+		if n.Data != "pre" {
+			return true
+		}
+		code := codeChild(n)
+		if code == nil {
+			return false
+		}
+		lang := codeLanguage(code)
+		if lang == "" {
+			lang = codeLanguage(n)
+		}
+		if lang == "" {
+			return false
+		}
+		lexer := lexers.Get(lang)
+		if lexer == nil {
+			return false
+		}
+
+		nodes, err := h.highlight(chroma.Coalesce(lexer), textContent(code))
+		if err != nil {
+			if utils.Log != nil {
+				utils.Log.WithError(err).WithField("lang", lang).Warn("代码高亮失败")
+			}
+			return false
+		}
+		wrapper := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+		setAttr(wrapper, "class", "highlight")
+		setAttr(wrapper, "data-lang", strings.ToLower(lexer.Config().Name))
+		for _, c := range nodes {
+			wrapper.AppendChild(c)
+		}
+		n.Parent.InsertBefore(wrapper, n)
+		n.Parent.RemoveChild(n)
+		return false
+	})
+}
+
+// 高亮代码并解析为节点，代码内容由 chroma 转义
+func (h *highlighter) highlight(lexer chroma.Lexer, code string) ([]*html.Node, error) {
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := h.formatter.Format(&b, h.style, iterator); err != nil {
+		return nil, err
+	}
+	parent := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	return html.ParseFragment(&b, parent)
+}
+
+// CSS 生成当前主题的样式表
+func (h *highlighter) CSS() (string, error) {
+	var b bytes.Buffer
+	if err := h.formatter.WriteCSS(&b, h.style); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+var (
+	highlightCSS     string
+	highlightCSSErr  error
+	highlightCSSOnce sync.Once
+)
+
+// HighlightCSS 返回代码高亮主题的样式表，按配置生成一次
+func HighlightCSS() (string, error) {
+	highlightCSSOnce.Do(func() {
+		highlightCSS, highlightCSSErr = newHighlighter(utils.AppConfig.Content.Highlight).CSS()
+	})
+	return highlightCSS, highlightCSSErr
+}
+
+// pre 中唯一的 code 子元素，忽略空白文本
+func codeChild(pre *html.Node) *html.Node {
+	var code *html.Node
+	for c := pre.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.ElementNode && c.Data == "code" && code == nil:
+			code = c
+		case c.Type == html.TextNode && strings.TrimSpace(c.Data) == "":
+		default:
+			return nil
+		}
+	}
+	return code
+}
+
+// 从 language-x 或 lang-x 类名中取出语言
+func codeLanguage(n *html.Node) string {
+	class, _ := getAttr(n, "class")
+	for _, c := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(c, prefix) && len(c) > len(prefix) {
+				return c[len(prefix):]
+			}
+		}
+	}
+	return ""
+}
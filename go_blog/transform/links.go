@@ -0,0 +1,66 @@
+package transform
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// externalLinks 为指向其他站点的链接添加 rel="nofollow noopener"
+type externalLinks struct{}
+
+func (externalLinks) Name() string { return "external-links" }
+
+func (externalLinks) Transform(root *html.Node, ctx *Context) {
+	walkElements(root, func(n *html.Node) bool {
+		if n.Data != "a" {
+			return true
+		}
+		href, ok := getAttr(n, "href")
+		if !ok {
+			return true
+		}
+		u, err := url.Parse(strings.TrimSpace(href))
+		if err != nil || u.Host == "" || strings.EqualFold(u.Hostname(), ctx.SiteHost) {
+			return true
+		}
+
+		rel, _ := getAttr(n, "rel")
+		tokens := strings.Fields(rel)
+		for _, want := range []string{"nofollow", "noopener"} {
+			found := false
+			for _, t := range tokens {
+				if strings.EqualFold(t, want) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				tokens = append(tokens, want)
+			}
+		}
+		setAttr(n, "rel", strings.Join(tokens, " "))
+		return true
+	})
+}
+
+// lazyImages 图片延迟加载，已指定 loading 的图片保持不变
+type lazyImages struct{}
+
+func (lazyImages) Name() string { return "lazy-images" }
+
+func (lazyImages) Transform(root *html.Node, ctx *Context) {
+	walkElements(root, func(n *html.Node) bool {
+		if n.Data != "img" {
+			return true
+		}
+		if _, ok := getAttr(n, "loading"); !ok {
+			setAttr(n, "loading", "lazy")
+		}
+		if _, ok := getAttr(n, "decoding"); !ok {
+			setAttr(n, "decoding", "async")
+		}
+		return true
+	})
+}
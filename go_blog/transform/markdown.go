@@ -0,0 +1,30 @@
+package transform
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// CommonMark + GFM（表格、删除线、自动链接、任务列表）+ 脚注。
+// 允许 Markdown 中的原始 HTML，输出会和 HTML 文章一样经过 sanitize 过滤
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		extension.Footnote,
+	),
+	goldmark.WithRendererOptions(
+		html.WithUnsafe(),
+	),
+)
+
+// RenderMarkdown 将 Markdown 渲染为 HTML
+func RenderMarkdown(source string) (string, error) {
+	var b bytes.Buffer
+	if err := markdown.Convert([]byte(source), &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,124 @@
+package transform
+
+import (
+	"go_blog/utils"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 保存地址的属性
+var urlAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"poster": true,
+}
+
+// 未配置改写规则时沿用爬虫来源站点
+var defaultRebaseRules = []utils.RebaseRule{
+	{Domain: "https://www.30secondsofcode.org", Default: true},
+}
+
+type rebaseRule struct {
+	base  *url.URL
+	paths []string
+}
+
+// rebaser 将以 / 开头的相对地址改写为来源站点的绝对地址
+type rebaser struct {
+	byHost   map[string]*rebaseRule
+	fallback *rebaseRule
+}
+
+func newRebaser(rules []utils.RebaseRule) *rebaser {
+	if len(rules) == 0 {
+		rules = defaultRebaseRules
+	}
+	r := &rebaser{byHost: make(map[string]*rebaseRule)}
+	for _, rule := range rules {
+		base, err := url.Parse(strings.TrimRight(rule.Domain, "/"))
+		if err != nil || base.Host == "" {
+			if utils.Log != nil {
+				utils.Log.WithField("domain", rule.Domain).Warn("忽略无效的地址改写规则")
+			}
+			continue
+		}
+		rr := &rebaseRule{base: base, paths: rule.Paths}
+		r.byHost[strings.ToLower(base.Hostname())] = rr
+		if rule.Default && r.fallback == nil {
+			r.fallback = rr
+		}
+	}
+	return r
+}
+
+func (r *rebaser) Name() string { return "rebase" }
+
+// 按文章来源查找规则，来源可以是站点地址或域名
+func (r *rebaser) rule(source string) *rebaseRule {
+	if source == "" {
+		return r.fallback
+	}
+	host := source
+	if u, err := url.Parse(source); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	if rule, ok := r.byHost[strings.ToLower(host)]; ok {
+		return rule
+	}
+	return r.fallback
+}
+
+func (r *rebaser) Transform(root *html.Node, ctx *Context) {
+	rule := r.rule(ctx.Source)
+	if rule == nil {
+		return
+	}
+	walkElements(root, func(n *html.Node) bool {
+		for i, a := range n.Attr {
+			switch {
+			case urlAttributes[a.Key]:
+				n.Attr[i].Val = rule.rebase(a.Val)
+			case a.Key == "srcset":
+				n.Attr[i].Val = rule.rebaseSrcset(a.Val)
+			}
+		}
+		return true
+	})
+}
+
+// 改写单个地址，只处理以 / 开头且不是 // 的地址
+func (rule *rebaseRule) rebase(value string) string {
+	v := strings.TrimSpace(value)
+	if !strings.HasPrefix(v, "/") || strings.HasPrefix(v, "//") {
+		return value
+	}
+	if len(rule.paths) > 0 {
+		matched := false
+		for _, p := range rule.paths {
+			if strings.HasPrefix(v, p) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return value
+		}
+	}
+	return rule.base.Scheme + "://" + rule.base.Host + v
+}
+
+// 改写 srcset 中的每个地址
+func (rule *rebaseRule) rebaseSrcset(value string) string {
+	candidates := strings.Split(value, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = rule.rebase(fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
@@ -0,0 +1,258 @@
+package transform
+
+import (
+	"go_blog/utils"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 连同内容一起删除的元素
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "form": true, "button": true,
+	"textarea": true, "select": true, "noscript": true, "template": true,
+	"link": true, "meta": true, "base": true, "title": true, "svg": true, "math": true,
+}
+
+// 允许的元素，其余元素只保留内容
+var allowedElements = map[string]bool{
+	"a": true, "abbr": true, "b": true, "blockquote": true, "br": true, "caption": true,
+	"cite": true, "code": true, "col": true, "colgroup": true, "dd": true, "del": true,
+	"details": true, "dfn": true, "div": true, "dl": true, "dt": true, "em": true,
+	"figcaption": true, "figure": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "hr": true, "i": true, "img": true, "input": true, "ins": true,
+	"kbd": true, "li": true, "mark": true, "ol": true, "p": true, "picture": true,
+	"pre": true, "q": true, "s": true, "samp": true, "section": true, "small": true,
+	"source": true, "span": true, "strong": true, "sub": true, "summary": true, "sup": true,
+	"table": true, "tbody": true, "td": true, "tfoot": true, "th": true, "thead": true,
+	"time": true, "tr": true, "u": true, "ul": true, "var": true, "video": true, "audio": true,
+}
+
+// 所有元素都允许的属性
+var globalAttributes = map[string]bool{
+	"id": true, "class": true, "title": true, "lang": true, "dir": true,
+}
+
+// 各元素额外允许的属性
+var elementAttributes = map[string]map[string]bool{
+	"a":          {"href": true, "name": true, "target": true, "rel": true},
+	"img":        {"src": true, "srcset": true, "alt": true, "width": true, "height": true, "loading": true, "decoding": true},
+	"source":     {"src": true, "srcset": true, "type": true, "media": true, "sizes": true},
+	"video":      {"src": true, "poster": true, "controls": true, "width": true, "height": true, "muted": true, "loop": true, "playsinline": true},
+	"audio":      {"src": true, "controls": true, "loop": true},
+	"td":         {"colspan": true, "rowspan": true, "align": true},
+	"th":         {"colspan": true, "rowspan": true, "align": true, "scope": true},
+	"col":        {"span": true},
+	"colgroup":   {"span": true},
+	"ol":         {"start": true, "reversed": true, "type": true},
+	"li":         {"value": true},
+	"blockquote": {"cite": true},
+	"q":          {"cite": true},
+	"del":        {"cite": true, "datetime": true},
+	"ins":        {"cite": true, "datetime": true},
+	"time":       {"datetime": true},
+	"details":    {"open": true},
+	"input":      {"type": true, "checked": true, "disabled": true},
+}
+
+// 原文中的 ID 和锚点名称统一添加的前缀，避免与文章页自身的元素 ID 冲突
+const userIDPrefix = "user-content-"
+
+// 允许的链接协议，相对地址始终允许
+var allowedSchemes = map[string]bool{
+	"http": true, "https": true, "mailto": true,
+}
+
+// sanitizer 基于白名单过滤 HTML，去掉脚本、事件属性和危险链接
+type sanitizer struct {
+	elements   map[string]bool
+	global     map[string]bool
+	attributes map[string]map[string]bool
+}
+
+func newSanitizer(cfg utils.SanitizeConfig) *sanitizer {
+	s := &sanitizer{
+		elements:   make(map[string]bool),
+		global:     make(map[string]bool),
+		attributes: make(map[string]map[string]bool),
+	}
+	for k := range allowedElements {
+		s.elements[k] = true
+	}
+	for k := range globalAttributes {
+		s.global[k] = true
+	}
+	for el, attrs := range elementAttributes {
+		s.attributes[el] = make(map[string]bool)
+		for k := range attrs {
+			s.attributes[el][k] = true
+		}
+	}
+
+	for _, el := range cfg.ExtraElements {
+		s.elements[strings.ToLower(el)] = true
+	}
+	for _, item := range cfg.ExtraAttributes {
+		el, attr, ok := strings.Cut(strings.ToLower(item), ":")
+		// 事件属性和内联样式无论如何都不允许
+		if !ok || strings.HasPrefix(attr, "on") || attr == "style" {
+			continue
+		}
+		if el == "*" {
+			s.global[attr] = true
+			continue
+		}
+		if s.attributes[el] == nil {
+			s.attributes[el] = make(map[string]bool)
+		}
+		s.attributes[el][attr] = true
+	}
+	return s
+}
+
+func (s *sanitizer) Name() string { return "sanitize" }
+
+func (s *sanitizer) Transform(root *html.Node, ctx *Context) {
+	s.clean(root)
+	prefixIDs(root)
+}
+
+func (s *sanitizer) clean(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode, html.DoctypeNode:
+			n.RemoveChild(c)
+		case html.ElementNode:
+			switch {
+			case droppedElements[c.Data] && !s.elements[c.Data]:
+				n.RemoveChild(c)
+			case !s.elements[c.Data] || !s.allowElement(c):
+				// 不在白名单中的元素只保留内容
+				s.clean(c)
+				for gc := c.FirstChild; gc != nil; {
+					gnext := gc.NextSibling
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+					gc = gnext
+				}
+				n.RemoveChild(c)
+			default:
+				s.cleanAttributes(c)
+				s.clean(c)
+			}
+		}
+		c = next
+	}
+}
+
+// 针对个别元素的额外限制
+func (s *sanitizer) allowElement(n *html.Node) bool {
+	if n.Data == "input" {
+		// 只保留任务列表的复选框，并且不可编辑
+		if t, _ := getAttr(n, "type"); strings.ToLower(t) != "checkbox" {
+			return false
+		}
+		setAttr(n, "disabled", "")
+	}
+	return true
+}
+
+func (s *sanitizer) cleanAttributes(n *html.Node) {
+	kept := n.Attr[:0]
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		if a.Namespace != "" || !(s.global[key] || s.attributes[n.Data][key]) {
+			continue
+		}
+		switch key {
+		case "href", "src", "poster", "cite":
+			if !safeURL(a.Val, n.Data == "img" && key == "src") {
+				continue
+			}
+		case "srcset":
+			if !safeSrcset(a.Val) {
+				continue
+			}
+		}
+		kept = append(kept, a)
+	}
+	n.Attr = kept
+}
+
+// 为原文中的 id 和锚点的 name 添加前缀，指向这些 ID 的页内链接同步改写，
+// 指向其他 ID（如之后生成的标题锚点）的链接保持不变
+func prefixIDs(root *html.Node) {
+	ids := make(map[string]bool)
+	walkElements(root, func(n *html.Node) bool {
+		for i, a := range n.Attr {
+			if a.Namespace == "" && (a.Key == "id" || a.Key == "name" && n.Data == "a") && a.Val != "" {
+				ids[a.Val] = true
+				n.Attr[i].Val = userIDPrefix + a.Val
+			}
+		}
+		return true
+	})
+	if len(ids) == 0 {
+		return
+	}
+
+	walkElements(root, func(n *html.Node) bool {
+		if n.Data != "a" {
+			return true
+		}
+		href, ok := getAttr(n, "href")
+		if !ok || !strings.HasPrefix(href, "#") {
+			return true
+		}
+		fragment := href[1:]
+		if unescaped, err := url.PathUnescape(fragment); err == nil {
+			fragment = unescaped
+		}
+		if ids[fragment] {
+			setAttr(n, "href", "#"+userIDPrefix+fragment)
+		}
+		return true
+	})
+}
+
+// 判断地址是否安全，allowDataImage 为 true 时允许 data:image/ 图片
+func safeURL(value string, allowDataImage bool) bool {
+	v := strings.TrimSpace(value)
+	// 浏览器会忽略协议中的空白和控制字符，先去掉再判断
+	v = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, v)
+	if allowDataImage && strings.HasPrefix(strings.ToLower(v), "data:image/") &&
+		!strings.HasPrefix(strings.ToLower(v), "data:image/svg") {
+		return true
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" {
+		// 相对地址的路径部分在第一个 /、? 或 # 之前不能出现冒号，避免被浏览器解析为协议
+		head := v
+		if i := strings.IndexAny(v, "/?#"); i >= 0 {
+			head = v[:i]
+		}
+		return !strings.Contains(head, ":")
+	}
+	return allowedSchemes[strings.ToLower(u.Scheme)]
+}
+
+func safeSrcset(value string) bool {
+	for _, c := range strings.Split(value, ",") {
+		fields := strings.Fields(c)
+		if len(fields) > 0 && !safeURL(fields[0], false) {
+			return false
+		}
+	}
+	return true
+}
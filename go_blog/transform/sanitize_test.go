@@ -0,0 +1,71 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 解析处理结果，返回所有元素的 ID 和链接地址
+func collectAttrs(t *testing.T, content string) (ids, hrefs []string) {
+	t.Helper()
+	root := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	walkElements(root, func(n *html.Node) bool {
+		if id, ok := getAttr(n, "id"); ok {
+			ids = append(ids, id)
+		}
+		if href, ok := getAttr(n, "href"); ok {
+			hrefs = append(hrefs, href)
+		}
+		return true
+	})
+	return ids, hrefs
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSanitizeReservedIDs(t *testing.T) {
+	p, err := New([]string{"sanitize", "heading-anchors"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	for id := range reservedIDs {
+		b.WriteString(`<div id="` + id + `">伪造</div><a href="#` + id + `">跳转</a>`)
+	}
+	b.WriteString(`<h2>Related</h2><a name="chat">旧式锚点</a><a href="#related-2">标题</a>`)
+
+	ids, hrefs := collectAttrs(t, p.Apply(b.String(), ""))
+	for id := range reservedIDs {
+		if contains(ids, id) {
+			t.Errorf("正文中出现了文章页使用的 ID %q", id)
+		}
+		if !contains(ids, userIDPrefix+id) {
+			t.Errorf("原文的 ID %q 应改为 %q", id, userIDPrefix+id)
+		}
+		if !contains(hrefs, "#"+userIDPrefix+id) {
+			t.Errorf("指向原文 ID 的链接应改为 #%s%s，实际 %v", userIDPrefix, id, hrefs)
+		}
+	}
+	// 生成的标题锚点避开保留 ID，指向它的链接不改写
+	if !contains(ids, "related-2") || !contains(hrefs, "#related-2") {
+		t.Errorf("标题锚点 = %v，链接 = %v，期望 related-2", ids, hrefs)
+	}
+}
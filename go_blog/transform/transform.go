@@ -0,0 +1,204 @@
+package transform
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go_blog/utils"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Context 处理一篇文章时的上下文
+type Context struct {
+	// Source 文章的来源站点，为空时使用默认的改写规则
+	Source string
+	// SiteHost 本站域名，用于判断外部链接
+	SiteHost string
+}
+
+// Transformer 正文处理步骤，直接修改解析后的节点树
+type Transformer interface {
+	// Name 步骤名称，与配置中的名称一致
+	Name() string
+	// Transform 处理以 root 为根的正文
+	Transform(root *html.Node, ctx *Context)
+}
+
+// 默认的处理顺序
+var defaultPipeline = []string{"rebase", "sanitize", "highlight", "heading-anchors", "external-links", "lazy-images"}
+
+// 处理步骤的实现发生变化、输出与之前不同时递增，使已缓存的处理结果失效
+const revision = 2
+
+// Pipeline 按顺序执行的正文处理步骤
+type Pipeline struct {
+	steps    []Transformer
+	siteHost string
+	version  string
+}
+
+// New 按名称顺序创建处理流程
+func New(names []string) (*Pipeline, error) {
+	cfg := utils.AppConfig.Content
+	p := &Pipeline{}
+	if base, err := url.Parse(utils.AppConfig.Server.BaseURL); err == nil {
+		p.siteHost = base.Hostname()
+	}
+
+	for _, name := range names {
+		var step Transformer
+		switch strings.ToLower(name) {
+		case "rebase":
+			step = newRebaser(cfg.Rebase)
+		case "sanitize":
+			step = newSanitizer(cfg.Sanitize)
+		case "highlight":
+			step = newHighlighter(cfg.Highlight)
+		case "heading-anchors":
+			step = headingAnchors{}
+		case "external-links":
+			step = externalLinks{}
+		case "lazy-images":
+			step = lazyImages{}
+		default:
+			return nil, fmt.Errorf("未知的正文处理步骤: %s", name)
+		}
+		p.steps = append(p.steps, step)
+	}
+
+	// 步骤、相关配置和实现版本任一变化，处理结果都可能不同
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d|%q|%s|%+v|%+v|%+v",
+		revision, names, p.siteHost, cfg.Rebase, cfg.Sanitize, cfg.Highlight)))
+	p.version = hex.EncodeToString(sum[:8])
+	return p, nil
+}
+
+// Version 处理流程的版本，用于区分不同配置下的处理结果缓存
+func (p *Pipeline) Version() string {
+	return p.version
+}
+
+// 根据配置创建的处理流程，Init 与后台 goroutine 可能同时访问，使用原子指针
+var current atomic.Pointer[Pipeline]
+
+// Init 根据配置创建处理流程
+func Init() error {
+	names := utils.AppConfig.Content.Pipeline
+	if len(names) == 0 {
+		names = defaultPipeline
+	}
+	hasSanitizer := false
+	for _, name := range names {
+		if strings.ToLower(name) == "sanitize" {
+			hasSanitizer = true
+		}
+	}
+	if !hasSanitizer && utils.Log != nil {
+		utils.Log.Warn("正文处理流程中没有 sanitize，文章中的脚本将原样输出")
+	}
+
+	p, err := New(names)
+	if err != nil {
+		return err
+	}
+	current.Store(p)
+	return nil
+}
+
+// Default 返回根据配置创建的处理流程，未初始化时按默认配置创建
+func Default() *Pipeline {
+	if p := current.Load(); p != nil {
+		return p
+	}
+	p, _ := New(defaultPipeline)
+	// 已有其他 goroutine 或 Init 先设置时使用已设置的流程
+	if current.CompareAndSwap(nil, p) {
+		return p
+	}
+	return current.Load()
+}
+
+// Apply 使用默认处理流程处理文章 HTML
+func Apply(content, source string) string {
+	return Default().Apply(content, source)
+}
+
+// Apply 解析 HTML 片段，依次执行各处理步骤后重新输出
+func (p *Pipeline) Apply(content, source string) string {
+	root := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), root)
+	if err != nil {
+		// 无法解析时不输出原文，避免绕过过滤
+		return html.EscapeString(content)
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	ctx := &Context{Source: source, SiteHost: p.siteHost}
+	for _, step := range p.steps {
+		step.Transform(root, ctx)
+	}
+
+	var b bytes.Buffer
+	for n := root.FirstChild; n != nil; n = n.NextSibling {
+		if err := html.Render(&b, n); err != nil {
+			return html.EscapeString(content)
+		}
+	}
+	return b.String()
+}
+
+// 深度优先遍历元素节点，fn 返回 false 时不再处理其子节点
+func walkElements(n *html.Node, fn func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode {
+			if fn(c) {
+				walkElements(c, fn)
+			}
+		}
+		c = next
+	}
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+// 元素中的全部文本
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
@@ -17,16 +17,154 @@ type Config struct {
 		Name     string `mapstructure:"name"`
 	} `mapstructure:"database"`
 	AI struct {
-		ApiKey string `mapstructure:"apiKey"`
-		Url    string `mapstructure:"url"`
-		Model  string `mapstructure:"model"`
-		Prompt string `mapstructure:"prompt"`
+		Provider        string                    `mapstructure:"provider"` // openai、ollama、anthropic 或 fake
+		ApiKey          string                    `mapstructure:"apiKey"`
+		Url             string                    `mapstructure:"url"`
+		Model           string                    `mapstructure:"model"`
+		Prompt          string                    `mapstructure:"prompt"`          // 默认摘要提示词模板（text/template）
+		CategoryPrompts map[string]string         `mapstructure:"categoryPrompts"` // 按文章分类覆盖的提示词模板
+		Styles          map[string]string         `mapstructure:"styles"`          // 按摘要风格命名的提示词模板
+		Providers       map[string]ProviderConfig `mapstructure:"providers"`
+		Client          ClientConfig              `mapstructure:"client"`
+		Models          []ModelConfig             `mapstructure:"models"`
+		MonthlyBudget   float64                   `mapstructure:"monthlyBudget"` // 每月费用上限，0 表示不限制
+		Chat            ChatConfig                `mapstructure:"chat"`
+		Translation     TranslationConfig         `mapstructure:"translation"`
+		Embedding       EmbeddingConfig           `mapstructure:"embedding"`
 	} `mapstructure:"ai"`
 	Server struct {
-		Host     string `mapstructure:"host"`
-		Port     string `mapstructure:"port"`
-		LogLevel string `mapstructure:"logLevel"`
+		Host         string `mapstructure:"host"`
+		Port         string `mapstructure:"port"`
+		LogLevel     string `mapstructure:"logLevel"`
+		BaseURL      string `mapstructure:"baseUrl"`      // 站点对外访问地址，用于生成订阅源中的绝对链接
+		MetricsToken string `mapstructure:"metricsToken"` // 访问 /metrics 需要携带的 Bearer Token，为空时不校验
+		// 信任的反向代理地址（IP 或 CIDR），仅来自这些地址的 X-Forwarded-For 才会用于识别客户端 IP
+		TrustedProxies []string `mapstructure:"trustedProxies"`
 	} `mapstructure:"server"`
+	Admin struct {
+		Username   string `mapstructure:"username"`
+		Password   string `mapstructure:"password"`
+		Secret     string `mapstructure:"secret"`
+		SessionTTL int    `mapstructure:"sessionTTL"` // 会话有效期（分钟）
+	} `mapstructure:"admin"`
+	Pagination struct {
+		PageSize    int `mapstructure:"pageSize"`    // 文章列表每页数量，默认 10
+		MaxPageSize int `mapstructure:"maxPageSize"` // 请求参数 page_size 的上限，默认 50
+	} `mapstructure:"pagination"`
+	Cache struct {
+		Pages string `mapstructure:"pages"` // 首页、列表页和文章页的 Cache-Control
+		Feeds string `mapstructure:"feeds"` // 订阅源的 Cache-Control
+	} `mapstructure:"cache"`
+	Search struct {
+		Backend         string `mapstructure:"backend"`         // memory 或 mysql
+		RebuildInterval int    `mapstructure:"rebuildInterval"` // 定时重建索引间隔（分钟），0 表示不重建
+	} `mapstructure:"search"`
+	Content struct {
+		Pipeline  []string        `mapstructure:"pipeline"` // 按顺序执行的正文处理步骤
+		Rebase    []RebaseRule    `mapstructure:"rebase"`   // 按来源站点改写相对地址
+		Sanitize  SanitizeConfig  `mapstructure:"sanitize"`
+		Highlight HighlightConfig `mapstructure:"highlight"` // 代码块语法高亮
+		Reading   ReadingConfig   `mapstructure:"reading"`   // 阅读时间估算
+	} `mapstructure:"content"`
+	RateLimit struct {
+		Summary     RateLimitConfig `mapstructure:"summary"`     // AI 摘要接口
+		Chat        RateLimitConfig `mapstructure:"chat"`        // 文章问答接口
+		Translation RateLimitConfig `mapstructure:"translation"` // 文章翻译（仅统计需要调用模型的请求）
+		Search      RateLimitConfig `mapstructure:"search"`      // 语义搜索（仅统计需要计算向量的搜索词）
+		Login       RateLimitConfig `mapstructure:"login"`       // 后台登录
+	} `mapstructure:"rateLimit"`
+}
+
+// ProviderConfig AI 服务商配置
+type ProviderConfig struct {
+	Url       string `mapstructure:"url"`
+	ApiKey    string `mapstructure:"apiKey"`
+	Model     string `mapstructure:"model"`
+	MaxTokens int    `mapstructure:"maxTokens"`
+	Version   string `mapstructure:"version"` // Anthropic 接口版本
+}
+
+// ClientConfig 调用 AI 服务的 HTTP 客户端配置
+type ClientConfig struct {
+	ConnectTimeout   int `mapstructure:"connectTimeout"`   // 建立连接超时（秒）
+	FirstByteTimeout int `mapstructure:"firstByteTimeout"` // 等待响应头超时（秒）
+	TotalTimeout     int `mapstructure:"totalTimeout"`     // 整个请求（含读取流）超时（秒）
+	MaxRetries       int `mapstructure:"maxRetries"`       // 429、5xx 和网络错误的最大重试次数
+	RetryBackoff     int `mapstructure:"retryBackoff"`     // 重试初始退避时间（毫秒）
+	BreakerThreshold int `mapstructure:"breakerThreshold"` // 连续失败多少次后熔断
+	BreakerCooldown  int `mapstructure:"breakerCooldown"`  // 熔断持续时间（秒）
+}
+
+// ModelConfig 模型相关配置
+type ModelConfig struct {
+	Name            string  `mapstructure:"name"`            // 模型名，default 表示默认配置
+	ChunkTokens     int     `mapstructure:"chunkTokens"`     // 长文章分块大小（token）
+	ChunkOverlap    int     `mapstructure:"chunkOverlap"`    // 相邻分块的重叠大小（token），负数表示不重叠
+	PromptPrice     float64 `mapstructure:"promptPrice"`     // 每百万输入 token 的价格
+	CompletionPrice float64 `mapstructure:"completionPrice"` // 每百万输出 token 的价格
+}
+
+// ChatConfig 文章问答配置，0 表示使用默认值
+type ChatConfig struct {
+	MaxTurns          int `mapstructure:"maxTurns"`          // 每个会话每篇文章最多提问的次数
+	HistoryTokens     int `mapstructure:"historyTokens"`     // 保留的历史对话 token 上限，超出时丢弃最早的对话
+	ContextTokens     int `mapstructure:"contextTokens"`     // 作为参考的文章内容 token 上限
+	MaxQuestionLength int `mapstructure:"maxQuestionLength"` // 单个问题的最大字符数
+	SessionTTL        int `mapstructure:"sessionTTL"`        // 会话闲置多久后清除（分钟）
+}
+
+// TranslationConfig 文章翻译配置
+type TranslationConfig struct {
+	Languages   []string `mapstructure:"languages"`   // 可翻译的目标语言代码，如 zh、en
+	BatchTokens int      `mapstructure:"batchTokens"` // 每次请求翻译的内容 token 上限
+}
+
+// EmbeddingConfig 文章向量配置
+type EmbeddingConfig struct {
+	Provider         string  `mapstructure:"provider"`         // openai、ollama 或 local，为空时跟随 AI.Provider
+	Url              string  `mapstructure:"url"`              // 为空时由对应服务商的接口地址推导
+	ApiKey           string  `mapstructure:"apiKey"`           // 为空时使用对应服务商的 apiKey
+	Model            string  `mapstructure:"model"`            // 向量模型
+	Dimensions       int     `mapstructure:"dimensions"`       // 本地向量的维度
+	BatchSize        int     `mapstructure:"batchSize"`        // 每次请求计算的文章数
+	BackfillInterval int     `mapstructure:"backfillInterval"` // 定时补全向量的间隔（分钟），0 表示只在启动时补全
+	MinScore         float64 `mapstructure:"minScore"`         // 语义检索和相关文章的最低相似度
+}
+
+// RebaseRule 来源站点的地址改写规则，将以 / 开头的相对地址改写为该站点的绝对地址
+type RebaseRule struct {
+	Domain  string   `mapstructure:"domain"`  // 来源站点，如 https://www.30secondsofcode.org
+	Default bool     `mapstructure:"default"` // 文章未记录来源时使用该规则
+	Paths   []string `mapstructure:"paths"`   // 只改写这些路径前缀，为空时改写所有相对地址
+}
+
+// SanitizeConfig HTML 白名单之外额外允许的元素和属性
+type SanitizeConfig struct {
+	ExtraElements   []string `mapstructure:"extraElements"`   // 如 iframe
+	ExtraAttributes []string `mapstructure:"extraAttributes"` // 格式为 元素:属性，* 表示所有元素，如 *:data-id
+}
+
+// HighlightConfig 代码块语法高亮配置
+type HighlightConfig struct {
+	Style       string `mapstructure:"style"`       // 配色主题，默认 github
+	LineNumbers bool   `mapstructure:"lineNumbers"` // 是否显示行号
+	TabWidth    int    `mapstructure:"tabWidth"`    // 制表符宽度，默认 4
+}
+
+// ReadingConfig 阅读速度，0 使用默认值
+type ReadingConfig struct {
+	CJKPerMinute   int `mapstructure:"cjkPerMinute"`   // 每分钟阅读的中日韩文字数，默认 300
+	WordsPerMinute int `mapstructure:"wordsPerMinute"` // 每分钟阅读的英文等单词数，默认 200
+	// 定时补全字数和阅读时间的间隔（分钟），默认 30，负数表示只在启动时补全
+	BackfillInterval int `mapstructure:"backfillInterval"`
+}
+
+// RateLimitConfig 令牌桶限流配置，速率为每分钟补充的令牌数，0 使用默认值，负数表示不限制
+type RateLimitConfig struct {
+	PerIP       float64 `mapstructure:"perIP"`       // 单个客户端 IP 的速率
+	PerIPBurst  int     `mapstructure:"perIPBurst"`  // 单个客户端 IP 的突发上限
+	Global      float64 `mapstructure:"global"`      // 全站总速率
+	GlobalBurst int     `mapstructure:"globalBurst"` // 全站突发上限
 }
 
 var AppConfig Config
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -115,6 +118,9 @@ func GinLogger() gin.HandlerFunc {
 		// 请求IP
 		clientIP := c.ClientIP()
 
+		// 请求ID
+		requestID := GetRequestID(c)
+
 		// 日志格式
 		Log.WithFields(logrus.Fields{
 			"status_code":  statusCode,
@@ -122,6 +128,61 @@ func GinLogger() gin.HandlerFunc {
 			"client_ip":    clientIP,
 			"req_method":   reqMethod,
 			"req_uri":      reqUri,
+			"request_id":   requestID,
 		}).Info()
 	}
 }
+
+// RequestIDHeader 传递请求 ID 的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// 请求 ID 在 gin.Context 中的键
+const requestIDKey = "requestID"
+
+// RequestID 中间件，为每个请求分配 ID 并写入响应头，
+// 反向代理传入了合法的 X-Request-ID 时沿用，便于串联上下游日志
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID 获取当前请求的 ID，未经过 RequestID 中间件时为空
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// RequestLog 带有请求 ID 的日志
+func RequestLog(c *gin.Context) *logrus.Entry {
+	return Log.WithField("request_id", GetRequestID(c))
+}
+
+// 生成随机的请求 ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// 只接受长度有限的字母、数字和 -_.，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
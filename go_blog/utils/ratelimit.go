@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// 客户端限流器闲置多久后回收
+const limiterIdleTTL = 10 * time.Minute
+
+// RateLimiter 令牌桶限流：每个客户端一个桶，另有一个全站共享的桶
+type RateLimiter struct {
+	perIP       rate.Limit
+	perIPBurst  int
+	global      *rate.Limiter
+	mu          sync.Mutex
+	clients     map[string]*clientLimiter
+	lastCleanup time.Time
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// 将每分钟的速率转换为令牌桶速率，负数表示不限制
+func perMinute(value, fallback float64) rate.Limit {
+	if value == 0 {
+		value = fallback
+	}
+	if value < 0 {
+		return rate.Inf
+	}
+	return rate.Limit(value / 60)
+}
+
+func burstOrDefault(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// NewRateLimiter 按配置创建限流器，未配置的项使用给定的默认值
+func NewRateLimiter(cfg, defaults RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		perIP:      perMinute(cfg.PerIP, defaults.PerIP),
+		perIPBurst: burstOrDefault(cfg.PerIPBurst, defaults.PerIPBurst),
+		global: rate.NewLimiter(perMinute(cfg.Global, defaults.Global),
+			burstOrDefault(cfg.GlobalBurst, defaults.GlobalBurst)),
+		clients:     make(map[string]*clientLimiter),
+		lastCleanup: time.Now(),
+	}
+}
+
+// Allow 为客户端取一个令牌，被限流时返回需要等待的时间。
+// 先检查客户端自己的桶，避免单个客户端耗尽全站额度
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	now := time.Now()
+	_, _, ok, wait := l.reserve(client, now)
+	return ok, wait
+}
+
+// Peek 检查客户端当前能否取得令牌但不消耗，用于只统计部分请求（如失败的登录）
+func (l *RateLimiter) Peek(client string) (bool, time.Duration) {
+	now := time.Now()
+	r, g, ok, wait := l.reserve(client, now)
+	if !ok {
+		return false, wait
+	}
+	g.CancelAt(now)
+	r.CancelAt(now)
+	return true, 0
+}
+
+// 依次从客户端的桶和全站的桶预留一个令牌，任一不足时撤销预留
+func (l *RateLimiter) reserve(client string, now time.Time) (*rate.Reservation, *rate.Reservation, bool, time.Duration) {
+	r := l.client(client, now).ReserveN(now, 1)
+	if !r.OK() {
+		return nil, nil, false, time.Minute
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return nil, nil, false, delay
+	}
+
+	g := l.global.ReserveN(now, 1)
+	if !g.OK() {
+		r.CancelAt(now)
+		return nil, nil, false, time.Minute
+	}
+	if delay := g.DelayFrom(now); delay > 0 {
+		g.CancelAt(now)
+		r.CancelAt(now)
+		return nil, nil, false, delay
+	}
+	return r, g, true, 0
+}
+
+// 获取客户端的令牌桶，并顺带回收长时间未访问的客户端
+func (l *RateLimiter) client(key string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastCleanup) > limiterIdleTTL {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > limiterIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.perIP, l.perIPBurst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// RetryAfterSeconds 将等待时间向上取整为 Retry-After 使用的秒数
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
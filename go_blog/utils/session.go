@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionCookieName 后台会话 Cookie 名称
+const SessionCookieName = "go_blog_session"
+
+// ChatCookieName 读者问答会话 Cookie 名称
+const ChatCookieName = "go_blog_chat"
+
+var (
+	sessionSecret     []byte
+	sessionSecretOnce sync.Once
+)
+
+// 获取会话签名密钥，未配置时随机生成（重启后会话失效）
+func getSessionSecret() []byte {
+	sessionSecretOnce.Do(func() {
+		if AppConfig.Admin.Secret != "" {
+			sessionSecret = []byte(AppConfig.Admin.Secret)
+			return
+		}
+		sessionSecret = make([]byte, 32)
+		if _, err := rand.Read(sessionSecret); err != nil {
+			panic(err)
+		}
+		if Log != nil {
+			Log.Warn("未配置 admin.secret，已使用随机密钥，重启后需要重新登录")
+		}
+	})
+	return sessionSecret
+}
+
+func sign(data string) string {
+	mac := hmac.New(sha256.New, getSessionSecret())
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SessionTTL 获取会话有效期
+func SessionTTL() time.Duration {
+	if AppConfig.Admin.SessionTTL <= 0 {
+		return 12 * time.Hour
+	}
+	return time.Duration(AppConfig.Admin.SessionTTL) * time.Minute
+}
+
+// CheckAdminCredentials 校验后台账号密码，未配置密码时禁止登录
+func CheckAdminCredentials(username, password string) bool {
+	if AppConfig.Admin.Username == "" || AppConfig.Admin.Password == "" {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(AppConfig.Admin.Username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(AppConfig.Admin.Password)) == 1
+	return userOK && passOK
+}
+
+// 会话签名包含密码摘要，修改密码后之前签发的会话全部失效
+func signSession(payload string) string {
+	digest := sha256.Sum256([]byte(AppConfig.Admin.Password))
+	return sign("session:" + hex.EncodeToString(digest[:]) + ":" + payload)
+}
+
+// NewSession 生成签名后的会话值，格式为 base64(用户名).过期时间.会话版本.随机数.签名
+func NewSession(username string, epoch int64) string {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	payload := fmt.Sprintf("%s.%d.%d.%s",
+		base64.RawURLEncoding.EncodeToString([]byte(username)),
+		time.Now().Add(SessionTTL()).Unix(),
+		epoch,
+		hex.EncodeToString(nonce))
+	return payload + "." + signSession(payload)
+}
+
+// ParseSession 校验会话值并返回用户名，会话版本与 epoch 不一致（已退出登录）时无效
+func ParseSession(value string, epoch int64) (string, bool) {
+	idx := strings.LastIndex(value, ".")
+	if idx < 0 {
+		return "", false
+	}
+	payload, signature := value[:idx], value[idx+1:]
+	if !hmac.Equal([]byte(signature), []byte(signSession(payload))) {
+		return "", false
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 4 {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+	if parts[2] != strconv.FormatInt(epoch, 10) {
+		return "", false
+	}
+	username, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	return string(username), true
+}
+
+// CSRFToken 根据会话值派生 CSRF Token
+func CSRFToken(session string) string {
+	return sign("csrf:" + session)
+}
+
+// CheckCSRFToken 校验 CSRF Token
+func CheckCSRFToken(session, token string) bool {
+	if token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(CSRFToken(session)))
+}
+
+// NewChatSession 生成签名后的问答会话值，格式为 随机ID.签名
+func NewChatSession() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	payload := hex.EncodeToString(id)
+	return payload + "." + sign("chat:"+payload)
+}
+
+// ParseChatSession 校验问答会话值并返回会话ID
+func ParseChatSession(value string) (string, bool) {
+	idx := strings.LastIndex(value, ".")
+	if idx < 0 {
+		return "", false
+	}
+	payload, signature := value[:idx], value[idx+1:]
+	if !hmac.Equal([]byte(signature), []byte(sign("chat:"+payload))) {
+		return "", false
+	}
+	return payload, true
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractText 提取HTML中的纯文本
+func ExtractText(htmlContent string) string {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return htmlContent
+	}
+
+	var buf bytes.Buffer
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data + " ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+	extract(doc)
+	return strings.TrimSpace(buf.String())
+}
+
+// TextBlock HTML 中的一个文本块（段落、标题、列表项等）
+type TextBlock struct {
+	Text    string
+	Heading bool
+}
+
+// 块级元素，遇到时切分文本块
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "blockquote": true,
+	"pre": true, "li": true, "ul": true, "ol": true, "table": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"br": true, "hr": true,
+}
+
+// ExtractBlocks 按段落和标题提取HTML中的文本块，用于按结构切分长文章
+func ExtractBlocks(htmlContent string) []TextBlock {
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		return []TextBlock{{Text: htmlContent}}
+	}
+
+	var blocks []TextBlock
+	var buf strings.Builder
+	flush := func(heading bool) {
+		text := strings.Join(strings.Fields(buf.String()), " ")
+		if text != "" {
+			blocks = append(blocks, TextBlock{Text: text, Heading: heading})
+		}
+		buf.Reset()
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data + " ")
+			return
+		}
+		if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+			return
+		}
+
+		isBlock := n.Type == html.ElementNode && blockElements[n.Data]
+		isHeading := isBlock && len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6'
+		if isBlock {
+			flush(false)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if isBlock {
+			flush(isHeading)
+		}
+	}
+	walk(doc)
+	flush(false)
+	return blocks
+}
+
+// IsCJK 判断字符是否为中日韩文字，这类文字按字数而不是单词数统计
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// CountWords 分别统计文本中的中日韩文字数和其他语言的单词数，
+// 单词为连续的字母或数字，标点和空白不计入
+func CountWords(text string) (cjk, words int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case IsCJK(r):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || (inWord && (r == '\'' || r == '’')):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		default:
+			inWord = false
+		}
+	}
+	return cjk, words
+}